@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"romulus/domain"
+	"romulus/query"
+	"romulus/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultSearchWindow = time.Hour
+
+// Handler serves the read side of romulus over http
+type Handler struct {
+	reader *storage.Reader
+	now    func() time.Time
+}
+
+func NewHandler(reader *storage.Reader) *Handler {
+	return &Handler{
+		reader: reader,
+		now:    time.Now,
+	}
+}
+
+// Register adds the query routes to a mux
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/traces/{traceid}", h.getTrace)
+	mux.HandleFunc("GET /api/search", h.search)
+	mux.HandleFunc("GET /api/query", h.query)
+}
+
+func (h *Handler) getTrace(w http.ResponseWriter, r *http.Request) {
+	tid, err := trace.TraceIDFromHex(r.PathValue("traceid"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	spans, err := h.reader.Trace(r.Context(), tid.String())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(spans) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("trace %s not found", tid))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, spans)
+}
+
+type searchResponse struct {
+	TraceIDs []string `json:"traceIds"`
+}
+
+// search finds traces with a span matching every `attr=key=value` parameter, between the
+// `start` and `end` parameters (RFC3339), which default to the last hour.  Keys are resolved
+// the same as in queries, so can be intrinsic fields such as `status=error`, or scoped such as
+// `resource.service.name=api`.
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	timeRange, err := h.parseRange(query.Get("start"), query.Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter := storage.SpanFilter{}
+	for _, attr := range query["attr"] {
+		key, value, found := strings.Cut(attr, "=")
+		if !found || key == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("attr %q must be in the form key=value", attr))
+			return
+		}
+
+		scope, key := storage.ScopeOf(key)
+		filter = append(filter, storage.Compare(key, storage.Equal, parseValue(value)).In(scope))
+	}
+
+	traceIds, err := h.reader.Filter(r.Context(), timeRange, filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := searchResponse{TraceIDs: make([]string, len(traceIds))}
+	for i, tid := range traceIds {
+		response.TraceIDs[i] = tid.String()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+type queryResponse struct {
+	TraceIDs   []string          `json:"traceIds"`
+	Spans      []*domain.Span    `json:"spans,omitempty"`
+	Aggregates []query.Aggregate `json:"aggregates,omitempty"`
+	Series     []query.Series    `json:"series,omitempty"`
+}
+
+// query runs the `q` parameter through the query language
+func (h *Handler) query(w http.ResponseWriter, r *http.Request) {
+	q, err := query.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	plan, err := query.Compile(q, h.now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := query.Execute(r.Context(), h.reader, plan)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	response := queryResponse{
+		TraceIDs:   make([]string, len(result.TraceIDs)),
+		Spans:      result.Spans,
+		Aggregates: result.Aggregates,
+		Series:     result.Series,
+	}
+	for i, tid := range result.TraceIDs {
+		response.TraceIDs[i] = tid.String()
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) parseRange(start, end string) (storage.Range, error) {
+	timeRange := storage.Range{Finish: h.now()}
+
+	if end != "" {
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return storage.Range{}, fmt.Errorf("end: %w", err)
+		}
+		timeRange.Finish = t
+	}
+
+	timeRange.Start = timeRange.Finish.Add(-defaultSearchWindow)
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return storage.Range{}, fmt.Errorf("start: %w", err)
+		}
+		timeRange.Start = t
+	}
+
+	if timeRange.Start.After(timeRange.Finish) {
+		return storage.Range{}, fmt.Errorf("start %s is after end %s", start, end)
+	}
+
+	return timeRange, nil
+}
+
+// parseValue picks the most specific type the value can be parsed as.  Quoting a value
+// forces it to be a string.
+func parseValue(value string) attribute.Value {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return attribute.StringValue(unquoted)
+	}
+
+	if value == "true" || value == "false" {
+		return attribute.BoolValue(value == "true")
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return attribute.Int64Value(i)
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return attribute.Float64Value(f)
+	}
+
+	return attribute.StringValue(value)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
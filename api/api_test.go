@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"romulus/domain"
+	"romulus/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestHandler(t *testing.T) {
+	start := time.Now().Add(-10 * time.Minute)
+	spans := storage.NewTestSpans(start)
+	tid := spans[0].SpanContext.TraceID()
+
+	backend := storage.NewMemoryBackend()
+	require.NoError(t, storage.NewWriter(backend, "testing").Write(t.Context(), spans))
+
+	mux := http.NewServeMux()
+	NewHandler(storage.NewReader(backend, "testing")).Register(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("get trace", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/traces/" + tid.String())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		read := []domain.Span{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&read))
+		require.Len(t, read, 2)
+	})
+
+	t.Run("get missing trace", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/traces/0102030405060708090a0b0c0d0e0f10")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("get invalid trace id", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/traces/nope")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	search := func(t *testing.T, query url.Values) searchResponse {
+		res, err := http.Get(server.URL + "/api/search?" + query.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		response := searchResponse{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		return response
+	}
+
+	t.Run("search by attribute", func(t *testing.T) {
+		response := search(t, url.Values{"attr": {"http.status_code=500", "retry=true"}})
+		require.Equal(t, []string{tid.String()}, response.TraceIDs)
+	})
+
+	t.Run("search intrinsics", func(t *testing.T) {
+		response := search(t, url.Values{"attr": {"name=child", "root=false"}})
+		require.Equal(t, []string{tid.String()}, response.TraceIDs)
+	})
+
+	t.Run("search with no match", func(t *testing.T) {
+		response := search(t, url.Values{"attr": {"http.status_code=200"}})
+		require.Empty(t, response.TraceIDs)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/query?" + url.Values{"q": {"where { http.status_code = 500 and retry = true } since 15m"}}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		response := queryResponse{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.Equal(t, []string{tid.String()}, response.TraceIDs)
+	})
+
+	t.Run("aggregation query", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/query?" + url.Values{"q": {"count() where retry = true since 15m"}}.Encode())
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		response := queryResponse{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.Len(t, response.Aggregates, 1)
+		require.Equal(t, "count()", response.Aggregates[0].Name)
+		require.Equal(t, 1.0, *response.Aggregates[0].Value)
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/api/query?" + url.Values{"q": {"where a ="}}.Encode())
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		for _, q := range []string{`where name matches "(unclosed"`, `count() where name matches "["`, `where retry > true`} {
+			res, err := http.Get(server.URL + "/api/query?" + url.Values{"q": {q}}.Encode())
+			require.NoError(t, err)
+			res.Body.Close()
+			require.Equal(t, http.StatusBadRequest, res.StatusCode, q)
+		}
+	})
+
+	t.Run("search outside time range", func(t *testing.T) {
+		response := search(t, url.Values{
+			"attr":  {"http.status_code=500"},
+			"start": {start.Add(-2 * time.Hour).Format(time.RFC3339)},
+			"end":   {start.Add(-1 * time.Hour).Format(time.RFC3339)},
+		})
+		require.Empty(t, response.TraceIDs)
+	})
+}
+
+func TestParseValue(t *testing.T) {
+	require.Equal(t, attribute.BoolValue(true), parseValue("true"))
+	require.Equal(t, attribute.Int64Value(1), parseValue("1"))
+	require.Equal(t, attribute.Float64Value(1.5), parseValue("1.5"))
+	require.Equal(t, attribute.StringValue("GET"), parseValue("GET"))
+	require.Equal(t, attribute.StringValue("200"), parseValue(`"200"`))
+}
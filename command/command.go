@@ -58,7 +58,8 @@ func (c *command) Run(args []string) int {
 		fmt.Fprintln(os.Stderr, err.Error())
 		return 1
 	}
-	defer shutdown(ctx)
+	// the context is cancelled by a signal, but pending spans should still be flushed
+	defer shutdown(context.WithoutCancel(ctx))
 
 	tr := otel.Tracer("romulus")
 	ctx, span := tr.Start(ctx, "main")
@@ -79,14 +80,20 @@ func (c *command) Run(args []string) int {
 	return 0
 }
 
+// withCancelSignals cancels the context on the first SIGINT or SIGTERM so the command can stop
+// cleanly, and exits straight away on a second, in case stopping is stuck
 func withCancelSignals(ctx context.Context) context.Context {
 	ctx, cancel := context.WithCancel(ctx)
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		s := <-signals
-		fmt.Printf("\nReceived %s, stopping\n", s)
+		fmt.Printf("\nReceived %s, stopping, signal again to exit immediately\n", s)
 		cancel()
+
+		s = <-signals
+		fmt.Fprintf(os.Stderr, "Received %s again, exiting\n", s)
+		os.Exit(1)
 	}()
 
 	return ctx
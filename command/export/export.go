@@ -0,0 +1,179 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"romulus/command"
+	"romulus/config"
+	"romulus/domain"
+	"romulus/otlp"
+	"romulus/query"
+	"romulus/storage"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func NewExportCommand() *ExportCommand {
+	return &ExportCommand{}
+}
+
+type ExportCommand struct {
+	endpoint  string
+	insecure  bool
+	output    string
+	batchSize int
+	timeRange command.RangeFlags
+	storage   command.StorageFlags
+}
+
+func (c *ExportCommand) Synopsis() string {
+	return "sends stored spans to an OTLP endpoint, or writes them to a file as OTLP JSON"
+}
+
+func (c *ExportCommand) Flags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("export", pflag.ContinueOnError)
+	flags.StringVar(&c.endpoint, "endpoint", "", "OTLP/gRPC endpoint to send to (default from OTEL_EXPORTER_OTLP_ENDPOINT, or localhost:4317)")
+	flags.BoolVar(&c.insecure, "insecure", false, "send to the endpoint without TLS")
+	flags.StringVar(&c.output, "output", "", "file to write OTLP JSON to, an export request per line, or - for stdout, instead of sending")
+	flags.IntVar(&c.batchSize, "batch-size", 500, "spans per export request")
+	c.timeRange.Register(flags)
+	c.storage.Register(flags)
+	return flags
+}
+
+// Execute exports every span in the time range, or with a query, every span of the traces it
+// matches
+func (c *ExportCommand) Execute(ctx context.Context, cfg *config.Config, args []string) error {
+	if c.batchSize < 1 {
+		return errors.New("batch size must be at least 1")
+	}
+
+	q, err := query.Parse(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+
+	if len(q.Aggregations) > 0 {
+		return errors.New("only traces can be exported, not aggregations")
+	}
+
+	if err := c.timeRange.Apply(q); err != nil {
+		return err
+	}
+
+	plan, err := query.Compile(q, time.Now())
+	if err != nil {
+		return err
+	}
+
+	backend, err := c.storage.Backend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	reader := storage.NewReader(backend, cfg.Dataset)
+
+	var dest sink
+	if c.output != "" {
+		dest, err = newFileSink(c.output)
+	} else {
+		dest, err = newGRPCSink(ctx, c.endpoint, c.insecure)
+	}
+	if err != nil {
+		return err
+	}
+
+	exported, batches, err := c.export(ctx, dest, selectSpans(ctx, reader, plan))
+	if closeErr := dest.close(context.WithoutCancel(ctx)); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d spans in %d batches\n", exported, batches)
+	return nil
+}
+
+// export sends the spans in batches, each regrouped by resource and scope
+func (c *ExportCommand) export(ctx context.Context, dest sink, spans iter.Seq2[*domain.Span, error]) (int, int, error) {
+	exported, batches := 0, 0
+	batch := make(domain.Spans, 0, c.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := dest.send(ctx, otlp.FromSpans(batch)); err != nil {
+			return err
+		}
+
+		exported += len(batch)
+		batches++
+		batch = batch[:0]
+		return nil
+	}
+
+	for span, err := range spans {
+		if err != nil {
+			return exported, batches, err
+		}
+
+		batch = append(batch, span)
+		if len(batch) == c.batchSize {
+			if err := flush(); err != nil {
+				return exported, batches, err
+			}
+		}
+	}
+
+	return exported, batches, flush()
+}
+
+// selectSpans streams the spans a plan selects.  Searches select whole traces, including any of
+// their spans outside the time range.
+func selectSpans(ctx context.Context, reader *storage.Reader, plan *query.Plan) iter.Seq2[*domain.Span, error] {
+	return func(yield func(*domain.Span, error) bool) {
+		if plan.Where == nil && plan.TraceID == "" {
+			for at, err := range reader.Spans(ctx, plan.Range, nil) {
+				if !yield(at.Span, err) || err != nil {
+					return
+				}
+			}
+			return
+		}
+
+		traceIds := []string{plan.TraceID}
+		if plan.TraceID == "" {
+			tids, err := reader.Filter(ctx, plan.Range, plan.Where)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			traceIds = make([]string, len(tids))
+			for i, tid := range tids {
+				traceIds[i] = tid.String()
+			}
+		}
+
+		for _, tid := range traceIds {
+			spans, err := reader.Trace(ctx, tid)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, span := range spans {
+				if !yield(span, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"context"
+	"romulus/domain"
+	"romulus/otlp"
+	"romulus/query"
+	"romulus/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+type recordingSink struct {
+	batches [][]*tracepb.ResourceSpans
+}
+
+func (s *recordingSink) send(ctx context.Context, batch []*tracepb.ResourceSpans) error {
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *recordingSink) close(ctx context.Context) error {
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	now := time.Now()
+	api := &domain.Resource{Resource: resource.NewSchemaless(attribute.String("service.name", "api"))}
+	db := &domain.Resource{Resource: resource.NewSchemaless(attribute.String("service.name", "db"))}
+
+	span := func(tid, sid byte, name string, res *domain.Resource) domain.Span {
+		return domain.Span{
+			Name:        name,
+			SpanContext: domain.SpanContext{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{tid}, SpanID: trace.SpanID{sid}})},
+			StartTime:   now.Add(-time.Minute),
+			EndTime:     now,
+			Resource:    res,
+		}
+	}
+
+	backend := storage.NewMemoryBackend()
+	require.NoError(t, storage.NewWriter(backend, "default").Write(t.Context(), []domain.Span{
+		span(1, 1, "GET /", api),
+		span(1, 2, "SELECT", db),
+		span(2, 3, "POST /", api),
+	}))
+	reader := storage.NewReader(backend, "default")
+
+	export := func(t *testing.T, input string, batchSize int) *recordingSink {
+		q, err := query.Parse(input)
+		require.NoError(t, err)
+		plan, err := query.Compile(q, now.Add(time.Second))
+		require.NoError(t, err)
+
+		dest := &recordingSink{}
+		cmd := &ExportCommand{batchSize: batchSize}
+		_, _, err = cmd.export(t.Context(), dest, selectSpans(t.Context(), reader, plan))
+		require.NoError(t, err)
+		return dest
+	}
+
+	names := func(t *testing.T, dest *recordingSink) []string {
+		names := []string{}
+		for _, batch := range dest.batches {
+			spans, _, err := otlp.ToSpans(batch)
+			require.NoError(t, err)
+			for _, span := range spans {
+				names = append(names, span.Name)
+			}
+		}
+		return names
+	}
+
+	t.Run("everything in range", func(t *testing.T) {
+		dest := export(t, "", 2)
+		require.Len(t, dest.batches, 2)
+		require.ElementsMatch(t, []string{"GET /", "SELECT", "POST /"}, names(t, dest))
+	})
+
+	t.Run("whole traces matching a query", func(t *testing.T) {
+		dest := export(t, `where name = "GET /"`, 10)
+		require.Len(t, dest.batches, 1)
+		// the batch is regrouped by resource
+		require.Len(t, dest.batches[0], 2)
+		require.ElementsMatch(t, []string{"GET /", "SELECT"}, names(t, dest))
+	})
+
+	t.Run("nothing matching", func(t *testing.T) {
+		dest := export(t, `where name = "DELETE /"`, 10)
+		require.Empty(t, dest.batches)
+	})
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"romulus/otlp"
+
+	otlpgrpc "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// sink is where exported batches are sent
+type sink interface {
+	send(ctx context.Context, batch []*tracepb.ResourceSpans) error
+	close(ctx context.Context) error
+}
+
+// grpcSink sends each batch as an export request to an OTLP/gRPC endpoint
+type grpcSink struct {
+	client interface {
+		UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error
+		Stop(ctx context.Context) error
+	}
+}
+
+func newGRPCSink(ctx context.Context, endpoint string, insecure bool) (*grpcSink, error) {
+	opts := []otlpgrpc.Option{}
+	if endpoint != "" {
+		opts = append(opts, otlpgrpc.WithEndpoint(endpoint))
+	}
+	if insecure {
+		opts = append(opts, otlpgrpc.WithInsecure())
+	}
+
+	client := otlpgrpc.NewClient(opts...)
+	if err := client.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	return &grpcSink{client: client}, nil
+}
+
+func (s *grpcSink) send(ctx context.Context, batch []*tracepb.ResourceSpans) error {
+	return s.client.UploadTraces(ctx, batch)
+}
+
+func (s *grpcSink) close(ctx context.Context) error {
+	return s.client.Stop(ctx)
+}
+
+// fileSink writes each batch as a line of OTLP JSON, which `romulus ingest` and the collector's
+// otlpjsonfile receiver can both read
+type fileSink struct {
+	out  *bufio.Writer
+	file io.Closer
+}
+
+func newFileSink(path string) (*fileSink, error) {
+	if path == "-" {
+		return &fileSink{out: bufio.NewWriter(os.Stdout), file: io.NopCloser(nil)}, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileSink{out: bufio.NewWriter(f), file: f}, nil
+}
+
+func (s *fileSink) send(ctx context.Context, batch []*tracepb.ResourceSpans) error {
+	b, err := otlp.MarshalJSON(&collectortrace.ExportTraceServiceRequest{ResourceSpans: batch})
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.out.Write(b); err != nil {
+		return err
+	}
+	return s.out.WriteByte('\n')
+}
+
+func (s *fileSink) close(ctx context.Context) error {
+	if err := s.out.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
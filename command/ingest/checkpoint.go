@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// checkpoint records how far through each file ingestion has got, so an interrupted ingest can
+// carry on where it stopped.  Offsets are the bytes of the (decompressed) file which have been
+// written to storage.  A batch can be written again after a crash, which is harmless as writing
+// a span is idempotent.
+type checkpoint struct {
+	path  string
+	Files map[string]progress `json:"files"`
+}
+
+type progress struct {
+	Offset   int64 `json:"offset"`
+	Complete bool  `json:"complete"`
+}
+
+// loadCheckpoint reads the checkpoint file, or starts a new one when it doesn't exist.  Without
+// a path nothing is recorded.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{path: path, Files: map[string]progress{}}
+	if path == "" {
+		return cp, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, cp); err != nil {
+		return nil, err
+	}
+	if cp.Files == nil {
+		cp.Files = map[string]progress{}
+	}
+
+	return cp, nil
+}
+
+func (c *checkpoint) get(file string) progress {
+	return c.Files[key(file)]
+}
+
+func (c *checkpoint) set(file string, p progress) error {
+	c.Files[key(file)] = p
+	return c.save()
+}
+
+// save replaces the checkpoint file in one step, so it is never left half written
+func (c *checkpoint) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// key makes files recorded with a relative path still match when run from another directory
+func key(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return file
+}
@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"romulus/storage"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checkpoint.json")
+
+	t.Run("missing file starts empty", func(t *testing.T) {
+		cp, err := loadCheckpoint(path)
+		require.NoError(t, err)
+		require.Empty(t, cp.Files)
+		require.NoFileExists(t, path)
+	})
+
+	t.Run("saved and loaded", func(t *testing.T) {
+		cp, err := loadCheckpoint(path)
+		require.NoError(t, err)
+		require.NoError(t, cp.set(filepath.Join(dir, "a.json"), progress{Offset: 42}))
+		require.NoError(t, cp.set(filepath.Join(dir, "b.json"), progress{Complete: true}))
+		require.NoFileExists(t, path+".tmp")
+
+		loaded, err := loadCheckpoint(path)
+		require.NoError(t, err)
+		require.Equal(t, progress{Offset: 42}, loaded.get(filepath.Join(dir, "a.json")))
+		require.Equal(t, progress{Complete: true}, loaded.get(filepath.Join(dir, "b.json")))
+		require.Equal(t, progress{}, loaded.get(filepath.Join(dir, "c.json")))
+	})
+
+	t.Run("relative paths match", func(t *testing.T) {
+		cp, err := loadCheckpoint(path)
+		require.NoError(t, err)
+		require.NoError(t, cp.set("relative.json", progress{Offset: 7}))
+
+		abs, err := filepath.Abs("relative.json")
+		require.NoError(t, err)
+		require.Equal(t, progress{Offset: 7}, cp.get(abs))
+	})
+
+	t.Run("without a path nothing is saved", func(t *testing.T) {
+		cp, err := loadCheckpoint("")
+		require.NoError(t, err)
+		require.NoError(t, cp.set("a.json", progress{Offset: 1}))
+		require.Equal(t, progress{Offset: 1}, cp.get("a.json"))
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		corrupt := filepath.Join(dir, "corrupt.json")
+		require.NoError(t, os.WriteFile(corrupt, []byte("{"), 0o644))
+
+		_, err := loadCheckpoint(corrupt)
+		require.Error(t, err)
+	})
+}
+
+func TestIngestFileCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	lines := []string{request("eee19b7ec3c1b171"), request("eee19b7ec3c1b172"), request("eee19b7ec3c1b173")}
+	file := filepath.Join(dir, "spans.jsonl")
+	require.NoError(t, os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	ingest := func(t *testing.T, cp *checkpoint) (totals, []string) {
+		backend := storage.NewMemoryBackend()
+		counts, err := NewIngestCommand().ingestFile(t.Context(), storage.NewWriter(backend, "default"), cp, file)
+		require.NoError(t, err)
+
+		spans, err := storage.NewReader(backend, "default").Trace(t.Context(), "5b8efff798038103d269b633813fc60c")
+		require.NoError(t, err)
+
+		ids := []string{}
+		for _, span := range spans {
+			ids = append(ids, span.SpanContext.SpanID().String())
+		}
+		return counts, ids
+	}
+
+	t.Run("progress is recorded", func(t *testing.T) {
+		cp, err := loadCheckpoint(filepath.Join(dir, "fresh.json"))
+		require.NoError(t, err)
+
+		counts, ids := ingest(t, cp)
+		require.Equal(t, totals{batches: 3, spans: 3}, counts)
+		require.Len(t, ids, 3)
+
+		loaded, err := loadCheckpoint(filepath.Join(dir, "fresh.json"))
+		require.NoError(t, err)
+		require.True(t, loaded.get(file).Complete)
+	})
+
+	t.Run("completed files are skipped", func(t *testing.T) {
+		cp, err := loadCheckpoint("")
+		require.NoError(t, err)
+		require.NoError(t, cp.set(file, progress{Complete: true}))
+
+		counts, ids := ingest(t, cp)
+		require.Equal(t, totals{}, counts)
+		require.Empty(t, ids)
+	})
+
+	t.Run("resumed mid file", func(t *testing.T) {
+		cp, err := loadCheckpoint("")
+		require.NoError(t, err)
+		require.NoError(t, cp.set(file, progress{Offset: int64(len(lines[0]))}))
+
+		counts, ids := ingest(t, cp)
+		require.Equal(t, totals{batches: 2, spans: 2}, counts)
+		require.ElementsMatch(t, []string{"eee19b7ec3c1b172", "eee19b7ec3c1b173"}, ids)
+		require.True(t, cp.get(file).Complete)
+	})
+}
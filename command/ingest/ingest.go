@@ -0,0 +1,267 @@
+package ingest
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"romulus/command"
+	"romulus/config"
+	"romulus/otlp"
+	"romulus/storage"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+)
+
+// progressEvery is how many batches are ingested between progress reports
+const progressEvery = 100
+
+// extensions are the files picked up when ingesting a directory
+var extensions = []string{".json", ".jsonl", ".ndjson", ".json.gz", ".jsonl.gz", ".ndjson.gz"}
+
+func NewIngestCommand() *IngestCommand {
+	return &IngestCommand{}
+}
+
+type IngestCommand struct {
+	checkpoint string
+	storage    command.StorageFlags
+}
+
+func (c *IngestCommand) Synopsis() string {
+	return "imports OTLP JSON files, a directory of them, or stdin with -"
+}
+
+func (c *IngestCommand) Flags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("ingest", pflag.ContinueOnError)
+	flags.StringVar(&c.checkpoint, "checkpoint", "", "file recording progress, so an interrupted ingest can be resumed by running it again")
+	c.storage.Register(flags)
+	return flags
+}
+
+// totals are the counts reported as ingestion progresses
+type totals struct {
+	batches  int
+	spans    int
+	rejected int
+}
+
+func (t *totals) add(other totals) {
+	t.batches += other.batches
+	t.spans += other.spans
+	t.rejected += other.rejected
+}
+
+func (t totals) String() string {
+	return fmt.Sprintf("%d batches, %d spans, %d rejected", t.batches, t.spans, t.rejected)
+}
+
+func (c *IngestCommand) Execute(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected files or directories to ingest, or - for stdin")
+	}
+
+	files, err := expand(args)
+	if err != nil {
+		return err
+	}
+
+	cp, err := loadCheckpoint(c.checkpoint)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+
+	backend, err := c.storage.Backend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	writer := storage.NewWriter(backend, cfg.Dataset)
+
+	all := totals{}
+	for _, file := range files {
+		counts, err := c.ingestFile(ctx, writer, cp, file)
+		all.add(counts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "ingested %d files: %s\n", len(files), all)
+	return nil
+}
+
+// expand replaces directories with the files in them, sorted so that a resumed ingest visits
+// them in the same order
+func expand(args []string) ([]string, error) {
+	files := []string{}
+
+	for _, arg := range args {
+		if arg == "-" {
+			files = append(files, arg)
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		found := []string{}
+		err = filepath.WalkDir(arg, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && slices.ContainsFunc(extensions, func(ext string) bool { return strings.HasSuffix(p, ext) }) {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		slices.Sort(found)
+		files = append(files, found...)
+	}
+
+	return files, nil
+}
+
+func (c *IngestCommand) ingestFile(ctx context.Context, writer *storage.Writer, cp *checkpoint, file string) (totals, error) {
+	report := func(counts totals) {
+		if counts.batches%progressEvery == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, counts)
+		}
+	}
+
+	// stdin can't be resumed, so is never checkpointed
+	if file == "-" {
+		counts, err := ingestStream(ctx, writer, os.Stdin, 0, func(_ int64, counts totals) error {
+			report(counts)
+			return nil
+		})
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "%s: done, %s\n", file, counts)
+		}
+		return counts, err
+	}
+
+	p := cp.get(file)
+	if p.Complete {
+		fmt.Fprintf(os.Stderr, "%s: already ingested, skipping\n", file)
+		return totals{}, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return totals{}, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return totals{}, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if p.Offset > 0 {
+		fmt.Fprintf(os.Stderr, "%s: resuming from byte %d\n", file, p.Offset)
+	}
+
+	counts, err := ingestStream(ctx, writer, r, p.Offset, func(offset int64, counts totals) error {
+		report(counts)
+		p.Offset = offset
+		return cp.set(file, p)
+	})
+	if err != nil {
+		return counts, err
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: done, %s\n", file, counts)
+	p.Complete = true
+	return counts, cp.set(file, p)
+}
+
+// ingestStream writes each export request in the stream, which can be a single json document or
+// many, such as one per line.  The first skip bytes have already been ingested.  After each
+// batch is written, done is called with the offset the next batch starts at.
+func ingestStream(ctx context.Context, writer *storage.Writer, r io.Reader, skip int64, done func(offset int64, counts totals) error) (totals, error) {
+	counts := totals{}
+
+	buffered := bufio.NewReader(r)
+	if _, err := io.CopyN(io.Discard, buffered, skip); err != nil {
+		return counts, fmt.Errorf("skipping to byte %d: %w", skip, err)
+	}
+
+	decoder := json.NewDecoder(buffered)
+	for {
+		// stopping between batches leaves the checkpoint pointing at the next one
+		if err := ctx.Err(); err != nil {
+			return counts, err
+		}
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return counts, nil
+		} else if err != nil {
+			return counts, fmt.Errorf("byte %d: %w", skip+decoder.InputOffset(), err)
+		}
+
+		// unknown fields are ignored when decoding, so anything else, such as the stdouttrace
+		// exporter's format, would otherwise be silently ingested as an empty batch
+		if !isExportRequest(raw) {
+			return counts, fmt.Errorf("byte %d: not an OTLP export request, there is no resourceSpans", skip+decoder.InputOffset())
+		}
+
+		req := &collectortrace.ExportTraceServiceRequest{}
+		if err := otlp.UnmarshalJSON(raw, req); err != nil {
+			return counts, fmt.Errorf("byte %d: %w", skip+decoder.InputOffset(), err)
+		}
+
+		// spans which can't be stored are reported, but don't stop the rest being ingested
+		spans, rejected, convertErr := otlp.ToSpans(req.GetResourceSpans())
+		if convertErr != nil {
+			fmt.Fprintf(os.Stderr, "byte %d: %s\n", skip+decoder.InputOffset(), convertErr)
+		}
+
+		if err := writer.Write(ctx, spans); err != nil {
+			return counts, err
+		}
+
+		counts.add(totals{batches: 1, spans: len(spans), rejected: rejected})
+		if err := done(skip+decoder.InputOffset(), counts); err != nil {
+			return counts, err
+		}
+	}
+}
+
+// isExportRequest checks the document is an object with resource spans, in either the camel or
+// snake case form protojson accepts.  An empty list of them is still a valid request.
+func isExportRequest(raw json.RawMessage) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return false
+	}
+
+	_, camel := fields["resourceSpans"]
+	_, snake := fields["resource_spans"]
+	return camel || snake
+}
@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"fmt"
+	"romulus/storage"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func request(spanId string) string {
+	return fmt.Sprintf(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"5b8efff798038103d269b633813fc60c","spanId":%q,"name":"GET /","startTimeUnixNano":"1544712660000000000","endTimeUnixNano":"1544712661000000000"}]}]}]}`, spanId)
+}
+
+func TestIngestStream(t *testing.T) {
+	lines := []string{request("eee19b7ec3c1b171"), request("eee19b7ec3c1b172"), request("eee19b7ec3c1b173")}
+	input := strings.Join(lines, "\n") + "\n"
+
+	backend := storage.NewMemoryBackend()
+	writer := storage.NewWriter(backend, "default")
+	reader := storage.NewReader(backend, "default")
+
+	offsets := []int64{}
+	done := func(offset int64, counts totals) error {
+		offsets = append(offsets, offset)
+		return nil
+	}
+
+	t.Run("every batch", func(t *testing.T) {
+		counts, err := ingestStream(t.Context(), writer, strings.NewReader(input), 0, done)
+		require.NoError(t, err)
+		require.Equal(t, totals{batches: 3, spans: 3}, counts)
+		require.Equal(t, []int64{int64(len(lines[0])), int64(len(lines[0]) + 1 + len(lines[1])), int64(len(input) - 1)}, offsets)
+
+		spans, err := reader.Trace(t.Context(), "5b8efff798038103d269b633813fc60c")
+		require.NoError(t, err)
+		require.Len(t, spans, 3)
+	})
+
+	t.Run("resumed", func(t *testing.T) {
+		offsets = nil
+		counts, err := ingestStream(t.Context(), writer, strings.NewReader(input), int64(len(lines[0])), done)
+		require.NoError(t, err)
+		require.Equal(t, totals{batches: 2, spans: 2}, counts)
+		require.Equal(t, int64(len(input)-1), offsets[len(offsets)-1])
+	})
+
+	t.Run("pretty printed", func(t *testing.T) {
+		pretty := strings.ReplaceAll(request("eee19b7ec3c1b174"), ",", ",\n  ")
+		counts, err := ingestStream(t.Context(), writer, strings.NewReader(pretty), 0, done)
+		require.NoError(t, err)
+		require.Equal(t, totals{batches: 1, spans: 1}, counts)
+
+		ids, err := reader.Filter(t.Context(), storage.Range{Start: time.Unix(1544712600, 0), Finish: time.Unix(1544712700, 0)})
+		require.NoError(t, err)
+		require.Len(t, ids, 1)
+	})
+
+	t.Run("not an export request", func(t *testing.T) {
+		// the shape of the stdouttrace exporter's output
+		stdout := `{"Name":"GET /","SpanContext":{"TraceID":"5b8efff798038103d269b633813fc60c","SpanID":"eee19b7ec3c1b175"}}`
+		counts, err := ingestStream(t.Context(), writer, strings.NewReader(lines[0]+"\n"+stdout), 0, done)
+		require.ErrorContains(t, err, "not an OTLP export request")
+		require.Equal(t, totals{batches: 1, spans: 1}, counts)
+	})
+
+	t.Run("empty export request", func(t *testing.T) {
+		counts, err := ingestStream(t.Context(), writer, strings.NewReader(`{"resourceSpans":[]}`), 0, done)
+		require.NoError(t, err)
+		require.Equal(t, totals{batches: 1}, counts)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := ingestStream(t.Context(), writer, strings.NewReader(lines[0]+"\n{not json"), 0, done)
+		require.ErrorContains(t, err, "byte")
+	})
+}
@@ -0,0 +1,133 @@
+package query
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// table is a result laid out as rows.  Cells are strings, numbers, times, or nil when there is
+// no value, and a nil *float64 is an aggregate with no value.
+type table struct {
+	columns []string
+	rows    [][]any
+}
+
+var formats = map[string]func(w io.Writer, t *table) error{
+	"table": writeTable,
+	"json":  writeJSONLines,
+	"csv":   writeCSV,
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// formatCell prints a cell for the text formats, with missing values left empty
+func formatCell(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(timeFormat)
+	}
+	return fmt.Sprint(cell)
+}
+
+// tableEscapes keeps a cell on its own line and in its own column
+var tableEscapes = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func writeTable(w io.Writer, t *table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if _, err := io.WriteString(tw, joinCells(t.columns)); err != nil {
+		return err
+	}
+
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = tableEscapes.Replace(formatCell(cell))
+		}
+		if _, err := io.WriteString(tw, joinCells(cells)); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+func joinCells(cells []string) string {
+	b := bytes.Buffer{}
+	for _, cell := range cells {
+		b.WriteString(cell)
+		b.WriteByte('\t')
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+func writeCSV(w io.Writer, t *table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.columns); err != nil {
+		return err
+	}
+
+	for _, row := range t.rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = formatCell(cell)
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSONLines prints each row as an object, with the keys in column order
+func writeJSONLines(w io.Writer, t *table) error {
+	for _, row := range t.rows {
+		b := bytes.Buffer{}
+		b.WriteByte('{')
+
+		for i, cell := range row {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			key, err := json.Marshal(t.columns[i])
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(cell)
+			if err != nil {
+				return err
+			}
+
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+
+		b.WriteString("}\n")
+		if _, err := w.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
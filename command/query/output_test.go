@@ -0,0 +1,70 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriters(t *testing.T) {
+	value := 1.5
+	var missing *float64
+	start := time.Date(2025, 6, 1, 15, 0, 0, 123000000, time.FixedZone("", 3600))
+
+	// the second row has no service, and an aggregate with no value
+	tbl := &table{
+		columns: []string{"service", "start", "count", "p99"},
+		rows: [][]any{
+			{"api", start, 2, &value},
+			{nil, start, 10, missing},
+			{`say "hi", then
+leave`, start, 0, 2.0},
+		},
+	}
+
+	t.Run("table", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		require.NoError(t, writeTable(out, tbl))
+		require.Equal(t, ""+
+			"service                start                     count  p99  \n"+
+			"api                    2025-06-01T14:00:00.123Z  2      1.5  \n"+
+			"                       2025-06-01T14:00:00.123Z  10          \n"+
+			`say "hi", then\nleave  2025-06-01T14:00:00.123Z  0      2    `+"\n",
+			out.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		require.NoError(t, writeCSV(out, tbl))
+		require.Equal(t, ""+
+			"service,start,count,p99\n"+
+			"api,2025-06-01T14:00:00.123Z,2,1.5\n"+
+			",2025-06-01T14:00:00.123Z,10,\n"+
+			"\"say \"\"hi\"\", then\nleave\",2025-06-01T14:00:00.123Z,0,2\n",
+			out.String())
+	})
+
+	t.Run("json lines", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		require.NoError(t, writeJSONLines(out, tbl))
+		require.Equal(t, ""+
+			`{"service":"api","start":"2025-06-01T15:00:00.123+01:00","count":2,"p99":1.5}`+"\n"+
+			`{"service":null,"start":"2025-06-01T15:00:00.123+01:00","count":10,"p99":null}`+"\n"+
+			`{"service":"say \"hi\", then\nleave","start":"2025-06-01T15:00:00.123+01:00","count":0,"p99":2}`+"\n",
+			out.String())
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		empty := &table{columns: []string{"a", "b"}}
+
+		out := &bytes.Buffer{}
+		require.NoError(t, writeCSV(out, empty))
+		require.Equal(t, "a,b\n", out.String())
+
+		out.Reset()
+		require.NoError(t, writeJSONLines(out, empty))
+		require.Empty(t, out.String())
+	})
+}
@@ -0,0 +1,267 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"romulus/command"
+	"romulus/config"
+	"romulus/domain"
+	querylang "romulus/query"
+	"romulus/storage"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// concurrentTraces is how many traces are read at once to summarise search results
+const concurrentTraces = 16
+
+// defaultTraceLimit is how many of a search's traces are read and summarised by default
+const defaultTraceLimit = 100
+
+func NewQueryCommand() *QueryCommand {
+	return &QueryCommand{}
+}
+
+type QueryCommand struct {
+	format    string
+	limit     int
+	timeRange command.RangeFlags
+	storage   command.StorageFlags
+}
+
+func (c *QueryCommand) Synopsis() string {
+	return "runs a query, printing the results as a table, json lines or csv"
+}
+
+func (c *QueryCommand) Flags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("query", pflag.ContinueOnError)
+	flags.StringVar(&c.format, "format", "table", "output format: table, json (an object per line) or csv")
+	flags.IntVar(&c.limit, "limit", defaultTraceLimit, "most traces to summarise for a search, or 0 for all of them")
+	c.timeRange.Register(flags)
+	c.storage.Register(flags)
+	return flags
+}
+
+func (c *QueryCommand) Execute(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected a query")
+	}
+
+	write, found := formats[c.format]
+	if !found {
+		return fmt.Errorf("unknown format %q, expected table, json or csv", c.format)
+	}
+
+	if c.limit < 0 {
+		return errors.New("limit can't be negative")
+	}
+
+	q, err := querylang.Parse(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+
+	if err := c.timeRange.Apply(q); err != nil {
+		return err
+	}
+
+	plan, err := querylang.Compile(q, time.Now())
+	if err != nil {
+		return err
+	}
+
+	backend, err := c.storage.Backend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	reader := storage.NewReader(backend, cfg.Dataset)
+	result, err := querylang.Execute(ctx, reader, plan)
+	if err != nil {
+		return err
+	}
+
+	if c.limit > 0 && len(result.TraceIDs) > c.limit {
+		fmt.Fprintf(os.Stderr, "showing %d of %d matching traces, use --limit to see more\n", c.limit, len(result.TraceIDs))
+	}
+
+	t, err := toTable(ctx, reader, plan, result, c.limit)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if err := write(out, t); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// toTable lays out whichever kind of result the query produced.  Searches summarise at most
+// limit traces, or all of them when it is zero.
+func toTable(ctx context.Context, reader *storage.Reader, plan *querylang.Plan, result *querylang.Result, limit int) (*table, error) {
+	switch {
+	case len(plan.Aggregations) > 0 && result.Series == nil:
+		return aggregatesTable(result.Aggregates), nil
+
+	case len(plan.Aggregations) > 0:
+		return seriesTable(plan, result.Series), nil
+
+	case plan.TraceID != "":
+		return spansTable(result.Spans), nil
+	}
+
+	return tracesTable(ctx, reader, result.TraceIDs, limit)
+}
+
+func aggregatesTable(aggregates []querylang.Aggregate) *table {
+	t := &table{}
+	row := []any{}
+	for _, agg := range aggregates {
+		t.columns = append(t.columns, agg.Name)
+		row = append(row, agg.Value)
+	}
+	t.rows = [][]any{row}
+	return t
+}
+
+// seriesTable has a row per point of each series, keyed by the group by fields, and the bucket
+// time when the query has one
+func seriesTable(plan *querylang.Plan, series []querylang.Series) *table {
+	t := &table{columns: slices.Clone(plan.GroupBy)}
+	if plan.Bucket > 0 {
+		t.columns = append(t.columns, "time")
+	}
+	for _, call := range plan.Aggregations {
+		t.columns = append(t.columns, call.String())
+	}
+
+	for _, s := range series {
+		for _, point := range s.Points {
+			row := []any{}
+			for _, field := range plan.GroupBy {
+				switch value, found := s.Key[field]; {
+				case s.Other:
+					row = append(row, "(other)")
+				case found:
+					row = append(row, value)
+				default:
+					row = append(row, nil)
+				}
+			}
+
+			if plan.Bucket > 0 {
+				row = append(row, point.Time)
+			}
+			for _, agg := range point.Aggregates {
+				row = append(row, agg.Value)
+			}
+
+			t.rows = append(t.rows, row)
+		}
+	}
+
+	return t
+}
+
+func spansTable(spans []*domain.Span) *table {
+	t := &table{columns: []string{"span_id", "parent_id", "name", "service", "start", "duration_ms", "status"}}
+
+	tree := domain.NewTrace(spans)
+	for node := range tree.Walk() {
+		span := node.Span
+
+		var parent any
+		if span.Parent.IsValid() {
+			parent = span.Parent.SpanID().String()
+		}
+
+		status, _ := span.Value(domain.StatusField)
+		duration, _ := span.Value(domain.DurationField)
+
+		t.rows = append(t.rows, []any{
+			span.SpanContext.SpanID().String(),
+			parent,
+			span.Name,
+			service(span),
+			span.StartTime,
+			duration.AsFloat64(),
+			status.AsString(),
+		})
+	}
+
+	return t
+}
+
+// tracesTable summarises each matching trace by its root span, most recent first.  A search
+// doesn't know when its traces started without reading them, so past the limit the traces are
+// picked by id, which keeps the output stable but isn't necessarily the most recent.
+func tracesTable(ctx context.Context, reader *storage.Reader, traceIds []oteltrace.TraceID, limit int) (*table, error) {
+	if limit > 0 && len(traceIds) > limit {
+		traceIds = slices.SortedFunc(slices.Values(traceIds), func(a, b oteltrace.TraceID) int {
+			return bytes.Compare(a[:], b[:])
+		})[:limit]
+	}
+
+	trees := make([]*domain.Trace, len(traceIds))
+
+	wg, ctx := errgroup.WithContext(ctx)
+	wg.SetLimit(concurrentTraces)
+	for i, tid := range traceIds {
+		wg.Go(func() error {
+			tree, err := reader.TraceTree(ctx, tid.String())
+			trees[i] = tree
+			return err
+		})
+	}
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(trees))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Or(trees[b].Start().Compare(trees[a].Start()), strings.Compare(traceIds[a].String(), traceIds[b].String()))
+	})
+
+	t := &table{columns: []string{"trace_id", "start", "root", "service", "duration_ms", "spans"}}
+	for _, i := range order {
+		tree := trees[i]
+
+		var root, svc any
+		if len(tree.Roots) > 0 {
+			root = tree.Roots[0].Span.Name
+			svc = service(tree.Roots[0].Span)
+		}
+
+		t.rows = append(t.rows, []any{
+			traceIds[i].String(),
+			tree.Start(),
+			root,
+			svc,
+			float64(tree.Duration()) / float64(time.Millisecond),
+			tree.Len(),
+		})
+	}
+
+	return t, nil
+}
+
+func service(span *domain.Span) any {
+	if value, found := span.Value("resource.service.name"); found {
+		return value.Emit()
+	}
+	return nil
+}
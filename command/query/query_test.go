@@ -0,0 +1,70 @@
+package query
+
+import (
+	"romulus/domain"
+	"romulus/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracesTable(t *testing.T) {
+	now := time.Now()
+
+	spans := []domain.Span{}
+	traceIds := []trace.TraceID{}
+	for i := range 3 {
+		tid := trace.TraceID{byte(i + 1)}
+		traceIds = append(traceIds, tid)
+
+		// later traces started earlier
+		start := now.Add(-time.Duration(i+1) * time.Minute)
+		spans = append(spans, domain.Span{
+			Name:        "root",
+			SpanContext: domain.SpanContext{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{byte(i + 1)}})},
+			StartTime:   start,
+			EndTime:     start.Add(time.Second),
+			Resource:    &domain.Resource{Resource: resource.NewSchemaless(attribute.String("service.name", "api"))},
+		})
+	}
+
+	backend := storage.NewMemoryBackend()
+	require.NoError(t, storage.NewWriter(backend, "default").Write(t.Context(), spans))
+	reader := storage.NewReader(backend, "default")
+
+	ids := func(t *testing.T, tbl *table) []string {
+		ids := []string{}
+		for _, row := range tbl.rows {
+			ids = append(ids, row[0].(string))
+		}
+		return ids
+	}
+
+	// the search's trace ids are in no particular order
+	reversed := []trace.TraceID{traceIds[2], traceIds[1], traceIds[0]}
+
+	t.Run("every trace, most recent first", func(t *testing.T) {
+		tbl, err := tracesTable(t.Context(), reader, reversed, 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{traceIds[0].String(), traceIds[1].String(), traceIds[2].String()}, ids(t, tbl))
+		require.Equal(t, "root", tbl.rows[0][2])
+		require.Equal(t, "api", tbl.rows[0][3])
+		require.Equal(t, 1000.0, tbl.rows[0][4])
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		tbl, err := tracesTable(t.Context(), reader, reversed, 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{traceIds[0].String(), traceIds[1].String()}, ids(t, tbl))
+	})
+
+	t.Run("under the limit", func(t *testing.T) {
+		tbl, err := tracesTable(t.Context(), reader, reversed, 5)
+		require.NoError(t, err)
+		require.Len(t, tbl.rows, 3)
+	})
+}
@@ -0,0 +1,173 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"romulus/api"
+	"romulus/command"
+	"romulus/config"
+	"romulus/receiver"
+	"romulus/storage"
+	"time"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+)
+
+func NewServeCommand() *ServeCommand {
+	return &ServeCommand{}
+}
+
+type ServeCommand struct {
+	grpcAddr        string
+	httpAddr        string
+	queryAddr       string
+	storage         command.StorageFlags
+	shutdownTimeout time.Duration
+}
+
+func (c *ServeCommand) Synopsis() string {
+	return "runs the OTLP ingestion receivers and the query api"
+}
+
+func (c *ServeCommand) Flags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("serve", pflag.ContinueOnError)
+	flags.StringVar(&c.grpcAddr, "otlp-grpc-addr", ":4317", "listen address for OTLP/gRPC ingestion, empty to disable")
+	flags.StringVar(&c.httpAddr, "otlp-http-addr", ":4318", "listen address for OTLP/HTTP ingestion, empty to disable")
+	flags.StringVar(&c.queryAddr, "query-addr", ":8080", "listen address for the query api, empty to disable")
+	c.storage.Register(flags)
+	flags.DurationVar(&c.shutdownTimeout, "shutdown-timeout", 30*time.Second, "how long to wait for in-flight requests to finish when stopping")
+	return flags
+}
+
+func (c *ServeCommand) Execute(ctx context.Context, cfg *config.Config, args []string) error {
+	backend, err := c.storage.Backend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	writer := storage.NewWriter(backend, cfg.Dataset)
+	reader := storage.NewReader(backend, cfg.Dataset)
+
+	// every listener is bound before anything is served, so "listening on" is only printed once
+	// the address is ours, and an address in use fails without leaving the other servers running
+	listeners := []net.Listener{}
+	listen := func(addr string) (net.Listener, error) {
+		if addr == "" {
+			return nil, nil
+		}
+
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+		return listener, nil
+	}
+
+	grpcListener, err := listen(c.grpcAddr)
+	if err != nil {
+		return err
+	}
+	httpListener, err := listen(c.httpAddr)
+	if err != nil {
+		return err
+	}
+	queryListener, err := listen(c.queryAddr)
+	if err != nil {
+		return err
+	}
+
+	wg, groupCtx := errgroup.WithContext(ctx)
+	shutdowns := []func(ctx context.Context) error{}
+
+	if grpcListener != nil {
+		server := receiver.NewGRPCServer(writer)
+
+		fmt.Println("OTLP/gRPC listening on", grpcListener.Addr())
+		wg.Go(func() error {
+			return server.Serve(grpcListener)
+		})
+		shutdowns = append(shutdowns, gracefulStop(server))
+	}
+
+	if httpListener != nil {
+		mux := http.NewServeMux()
+		receiver.NewHTTPHandler(writer).Register(mux)
+
+		server := &http.Server{Handler: mux}
+		fmt.Println("OTLP/HTTP listening on", httpListener.Addr())
+		wg.Go(func() error {
+			return serve(server, httpListener)
+		})
+		shutdowns = append(shutdowns, server.Shutdown)
+	}
+
+	if queryListener != nil {
+		mux := http.NewServeMux()
+		api.NewHandler(reader).Register(mux)
+
+		server := &http.Server{Handler: mux}
+		fmt.Println("query api listening on", queryListener.Addr())
+		wg.Go(func() error {
+			return serve(server, queryListener)
+		})
+		shutdowns = append(shutdowns, server.Shutdown)
+	}
+
+	if len(shutdowns) == 0 {
+		return errors.New("all listeners are disabled, nothing to serve")
+	}
+
+	// stop when signalled, or when any of the servers fails.  Shutting down stops accepting new
+	// requests, and waits for the in-flight ones (and so their writes) to complete.
+	wg.Go(func() error {
+		<-groupCtx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.shutdownTimeout)
+		defer cancel()
+
+		errs := make([]error, len(shutdowns))
+		for i, shutdown := range shutdowns {
+			errs[i] = shutdown(shutdownCtx)
+		}
+
+		return errors.Join(errs...)
+	})
+
+	return wg.Wait()
+}
+
+func serve(server *http.Server, listener net.Listener) error {
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// gracefulStop waits for running rpcs to finish, unless the context expires first
+func gracefulStop(server *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package command
+
+import (
+	"context"
+	"romulus/config"
+	"romulus/storage"
+
+	"github.com/spf13/pflag"
+)
+
+// StorageFlags are the flags for choosing a storage backend, shared by every command which reads
+// or writes spans.  They override the config from the environment.
+type StorageFlags struct {
+	storage     string
+	storageRoot string
+}
+
+func (f *StorageFlags) Register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.storage, "storage", "", "storage backend: s3, fs or memory (default from ROMULUS_STORAGE)")
+	flags.StringVar(&f.storageRoot, "storage-root", "", "directory for the fs storage backend (default from ROMULUS_STORAGE_ROOT)")
+}
+
+// Backend creates the backend selected by the flags and config
+func (f *StorageFlags) Backend(ctx context.Context, cfg *config.Config) (storage.Backend, error) {
+	if f.storage != "" {
+		cfg.Storage = f.storage
+	}
+	if f.storageRoot != "" {
+		cfg.StorageRoot = f.storageRoot
+	}
+
+	return storage.NewBackend(ctx, cfg)
+}
@@ -0,0 +1,52 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"romulus/query"
+
+	"github.com/spf13/pflag"
+)
+
+// RangeFlags set the time range of a query from the command line, taking precedence over the
+// query's since and until
+type RangeFlags struct {
+	since string
+	from  string
+	to    string
+}
+
+func (f *RangeFlags) Register(flags *pflag.FlagSet) {
+	flags.StringVar(&f.since, "since", "", "start of the time range, such as 15m, overriding the query's since")
+	flags.StringVar(&f.from, "from", "", "start of the time range, relative or RFC3339, overriding the query's since")
+	flags.StringVar(&f.to, "to", "", "end of the time range, relative or RFC3339, overriding the query's until")
+}
+
+// Apply replaces the query's time range with the flags'
+func (f *RangeFlags) Apply(q *query.Query) error {
+	if f.since != "" && f.from != "" {
+		return errors.New("only one of --since and --from can be used")
+	}
+
+	for _, flag := range []struct {
+		name  string
+		value string
+		ref   **query.TimeRef
+	}{
+		{"since", f.since, &q.Since},
+		{"from", f.from, &q.Since},
+		{"to", f.to, &q.Until},
+	} {
+		if flag.value == "" {
+			continue
+		}
+
+		ref, err := query.ParseTimeRef(flag.value)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", flag.name, err)
+		}
+		*flag.ref = ref
+	}
+
+	return nil
+}
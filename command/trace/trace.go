@@ -0,0 +1,110 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"romulus/command"
+	"romulus/config"
+	"romulus/domain"
+	"romulus/otlp"
+	"romulus/storage"
+	"slices"
+
+	"github.com/spf13/pflag"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+)
+
+var formats = []string{"waterfall", "json", "otlp-json"}
+
+func NewTraceCommand() *TraceCommand {
+	return &TraceCommand{}
+}
+
+type TraceCommand struct {
+	format  string
+	width   int
+	storage command.StorageFlags
+}
+
+func (c *TraceCommand) Synopsis() string {
+	return "prints a trace as a waterfall, or as json"
+}
+
+func (c *TraceCommand) Flags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("trace", pflag.ContinueOnError)
+	flags.StringVar(&c.format, "format", "waterfall", "output format: waterfall, json or otlp-json")
+	flags.IntVar(&c.width, "width", 40, "width of the duration bars in the waterfall")
+	c.storage.Register(flags)
+	return flags
+}
+
+func (c *TraceCommand) Execute(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected a single trace id")
+	}
+
+	if !slices.Contains(formats, c.format) {
+		return fmt.Errorf("unknown format %q, expected waterfall, json or otlp-json", c.format)
+	}
+
+	if c.width < 1 {
+		return errors.New("width must be at least 1")
+	}
+
+	tid, err := oteltrace.TraceIDFromHex(args[0])
+	if err != nil {
+		return err
+	}
+
+	backend, err := c.storage.Backend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	tree, err := storage.NewReader(backend, cfg.Dataset).TraceTree(ctx, tid.String())
+	if err != nil {
+		return err
+	}
+
+	if tree.Len() == 0 {
+		return fmt.Errorf("trace %s not found", tid)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+
+	switch c.format {
+	case "waterfall":
+		writeWaterfall(out, tid, tree, c.width)
+
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(ordered(tree)); err != nil {
+			return err
+		}
+
+	case "otlp-json":
+		b, err := otlp.MarshalJSON(&collectortrace.ExportTraceServiceRequest{ResourceSpans: otlp.FromSpans(ordered(tree))})
+		if err != nil {
+			return err
+		}
+		out.Write(b)
+		out.WriteByte('\n')
+	}
+
+	return out.Flush()
+}
+
+// ordered lists the spans depth first, so json output reads in the same order as the waterfall
+func ordered(tree *domain.Trace) domain.Spans {
+	spans := make(domain.Spans, 0, tree.Len())
+	for node := range tree.Walk() {
+		spans = append(spans, node.Span)
+	}
+	return spans
+}
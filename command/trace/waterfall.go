@@ -0,0 +1,109 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"romulus/domain"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// row is a line of the waterfall, before padding
+type row struct {
+	marker   string
+	name     string
+	service  string
+	duration string
+	bar      string
+}
+
+// writeWaterfall prints each span on a line, indented under its parent, with a bar showing when
+// it ran relative to the whole trace.  Spans with an error status are marked with a `!`, and
+// spans whose parent is missing are labelled as orphans.
+func writeWaterfall(w io.Writer, tid oteltrace.TraceID, tree *domain.Trace, width int) {
+	start := tree.Start()
+	total := tree.Duration()
+
+	fmt.Fprintf(w, "trace %s, %d spans, %s\n", tid, tree.Len(), formatDuration(total))
+	if missing := tree.MissingParents(); len(missing) > 0 {
+		ids := make([]string, len(missing))
+		for i, sid := range missing {
+			ids[i] = sid.String()
+		}
+		fmt.Fprintf(w, "missing parents: %s\n", strings.Join(ids, ", "))
+	}
+	fmt.Fprintln(w)
+
+	rows := []row{}
+	nameWidth, serviceWidth, durationWidth := 0, 0, 0
+
+	for node := range tree.Walk() {
+		span := node.Span
+
+		r := row{
+			marker:   " ",
+			name:     strings.Repeat("  ", node.Depth) + span.Name,
+			duration: formatDuration(span.EndTime.Sub(span.StartTime)),
+			bar:      bar(span.StartTime.Sub(start), span.EndTime.Sub(span.StartTime), total, width),
+		}
+
+		if span.Status.Code == codes.Error {
+			r.marker = "!"
+		}
+		if node.Parent == nil && span.Parent.IsValid() {
+			r.name += " (orphan)"
+		}
+		if service, found := span.Value("resource.service.name"); found {
+			r.service = service.Emit()
+		}
+
+		nameWidth = max(nameWidth, utf8.RuneCountInString(r.name))
+		serviceWidth = max(serviceWidth, utf8.RuneCountInString(r.service))
+		durationWidth = max(durationWidth, utf8.RuneCountInString(r.duration))
+		rows = append(rows, r)
+	}
+
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s %s  %s  %s  |%s|\n",
+			r.marker,
+			pad(r.name, nameWidth),
+			pad(r.service, serviceWidth),
+			strings.Repeat(" ", durationWidth-utf8.RuneCountInString(r.duration))+r.duration,
+			pad(r.bar, width),
+		)
+	}
+}
+
+// bar draws a span's position in the trace, scaled so the whole trace is width characters.
+// Every span is at least one character, however short.
+func bar(offset, duration, total time.Duration, width int) string {
+	if total <= 0 {
+		return strings.Repeat("█", width)
+	}
+
+	from := min(int(int64(offset)*int64(width)/int64(total)), width-1)
+	length := max(int(int64(duration)*int64(width)/int64(total)), 1)
+	length = min(length, width-from)
+
+	return strings.Repeat(" ", from) + strings.Repeat("█", length)
+}
+
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0))
+}
+
+// formatDuration rounds a duration to at most three decimal places of its unit
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
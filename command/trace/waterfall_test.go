@@ -0,0 +1,155 @@
+package trace
+
+import (
+	"bytes"
+	"romulus/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tid   = oteltrace.TraceID{0xaa}
+	start = time.Date(2025, 6, 1, 15, 0, 0, 0, time.UTC)
+)
+
+func waterfallSpan(sid, parent byte, name string, from, to time.Duration) *domain.Span {
+	span := &domain.Span{
+		Name:        name,
+		SpanContext: domain.SpanContext{SpanContext: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{TraceID: tid, SpanID: oteltrace.SpanID{sid}})},
+		StartTime:   start.Add(from),
+		EndTime:     start.Add(to),
+		Resource:    &domain.Resource{Resource: resource.NewSchemaless(attribute.String("service.name", "api"))},
+	}
+	if parent != 0 {
+		span.Parent = domain.SpanContext{SpanContext: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{TraceID: tid, SpanID: oteltrace.SpanID{parent}})}
+	}
+	return span
+}
+
+func TestWriteWaterfall(t *testing.T) {
+	failed := waterfallSpan(3, 1, "SELECT", 5*time.Second, 10*time.Second)
+	failed.Status = sdktrace.Status{Code: codes.Error}
+
+	cases := []struct {
+		Name     string
+		Spans    domain.Spans
+		Expected string
+	}{
+		{
+			Name: "nested",
+			Spans: domain.Spans{
+				waterfallSpan(1, 0, "GET /", 0, 10*time.Second),
+				waterfallSpan(2, 1, "auth", 0, 2500*time.Millisecond),
+				failed,
+			},
+			Expected: "" +
+				"trace aa000000000000000000000000000000, 3 spans, 10s\n" +
+				"\n" +
+				"  GET /     api   10s  |██████████|\n" +
+				"    auth    api  2.5s  |██        |\n" +
+				"!   SELECT  api    5s  |     █████|\n",
+		},
+		{
+			Name: "zero duration",
+			Spans: domain.Spans{
+				waterfallSpan(1, 0, "instant", 0, 0),
+				waterfallSpan(2, 1, "also instant", 0, 0),
+			},
+			Expected: "" +
+				"trace aa000000000000000000000000000000, 2 spans, 0s\n" +
+				"\n" +
+				"  instant         api  0s  |██████████|\n" +
+				"    also instant  api  0s  |██████████|\n",
+		},
+		{
+			Name: "orphans",
+			Spans: domain.Spans{
+				waterfallSpan(1, 0, "root", 0, 4*time.Second),
+				waterfallSpan(2, 9, "lost", 6*time.Second, 8*time.Second),
+				waterfallSpan(3, 2, "found", 7*time.Second, 8*time.Second),
+			},
+			Expected: "" +
+				"trace aa000000000000000000000000000000, 3 spans, 8s\n" +
+				"missing parents: 0900000000000000\n" +
+				"\n" +
+				"  root           api  4s  |█████     |\n" +
+				"  lost (orphan)  api  2s  |       ██ |\n" +
+				"    found        api  1s  |        █ |\n",
+		},
+		{
+			Name: "child starting before its parent",
+			Spans: domain.Spans{
+				waterfallSpan(1, 0, "parent", 5*time.Second, 10*time.Second),
+				waterfallSpan(2, 1, "early", 0, 6*time.Second),
+			},
+			Expected: "" +
+				"trace aa000000000000000000000000000000, 2 spans, 10s\n" +
+				"\n" +
+				"  parent   api  5s  |     █████|\n" +
+				"    early  api  6s  |██████    |\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			writeWaterfall(out, tid, domain.NewTrace(tc.Spans), 10)
+			require.Equal(t, tc.Expected, out.String())
+		})
+	}
+}
+
+func TestBar(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Offset   time.Duration
+		Duration time.Duration
+		Total    time.Duration
+		Expected string
+	}{
+		{"whole trace", 0, 10 * time.Second, 10 * time.Second, "██████████"},
+		{"first half", 0, 5 * time.Second, 10 * time.Second, "█████"},
+		{"second half", 5 * time.Second, 5 * time.Second, 10 * time.Second, "     █████"},
+		{"rounds down", 1900 * time.Millisecond, 2900 * time.Millisecond, 10 * time.Second, " ██"},
+		{"too short to see", 3 * time.Second, time.Nanosecond, 10 * time.Second, "   █"},
+		{"zero duration at the end", 10 * time.Second, 0, 10 * time.Second, "         █"},
+		{"clipped to the width", 8 * time.Second, 5 * time.Second, 10 * time.Second, "        ██"},
+		{"zero length trace", 0, 0, 0, "██████████"},
+		{"nanosecond trace", 0, time.Nanosecond, time.Nanosecond, "██████████"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.Equal(t, tc.Expected, bar(tc.Offset, tc.Duration, tc.Total, 10))
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	cases := []struct {
+		Duration time.Duration
+		Expected string
+	}{
+		{0, "0s"},
+		{450 * time.Nanosecond, "450ns"},
+		{1500 * time.Nanosecond, "1.5µs"},
+		{time.Millisecond + 234567*time.Nanosecond, "1.235ms"},
+		{999999999 * time.Nanosecond, "1s"},
+		{12*time.Second + 345678*time.Microsecond, "12.346s"},
+		{90 * time.Minute, "1h30m0s"},
+		{-2 * time.Millisecond, "-2ms"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Expected, func(t *testing.T) {
+			require.Equal(t, tc.Expected, formatDuration(tc.Duration))
+		})
+	}
+}
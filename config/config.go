@@ -2,14 +2,65 @@ package config
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strconv"
 )
 
 type Config struct {
 	DatabaseFile string
+
+	// Storage selects the backend: s3, fs or memory
+	Storage string
+	// StorageRoot is the directory the fs backend writes to
+	StorageRoot string
+
+	// Bucket is the s3 bucket all datasets are stored in
+	Bucket string
+	// KeyPrefix is prepended to every key, so several teams can share one bucket
+	KeyPrefix string
+	// Dataset is the top level directory of the span layout
+	Dataset string
+	// Endpoint overrides the s3 endpoint url, e.g. for a local s3 compatible store
+	Endpoint string
+	// PathStyle addresses buckets as a path rather than a subdomain
+	PathStyle bool
 }
 
 func CreateConfig(ctx context.Context) (*Config, error) {
+	pathStyle, err := boolEnv("ROMULUS_S3_PATH_STYLE", false)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		DatabaseFile: "dev.sqlite",
+		Storage:      stringEnv("ROMULUS_STORAGE", "s3"),
+		StorageRoot:  stringEnv("ROMULUS_STORAGE_ROOT", "data"),
+		Bucket:       stringEnv("ROMULUS_BUCKET", "romulus"),
+		KeyPrefix:    stringEnv("ROMULUS_KEY_PREFIX", ""),
+		Dataset:      stringEnv("ROMULUS_DATASET", "default"),
+		Endpoint:     stringEnv("ROMULUS_S3_ENDPOINT", ""),
+		PathStyle:    pathStyle,
 	}, nil
 }
+
+func stringEnv(name string, defaultValue string) string {
+	if val, found := os.LookupEnv(name); found {
+		return val
+	}
+	return defaultValue
+}
+
+func boolEnv(name string, defaultValue bool) (bool, error) {
+	val, found := os.LookupEnv(name)
+	if !found || val == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", name, err)
+	}
+	return b, nil
+}
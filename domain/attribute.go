@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"go.opentelemetry.io/otel/attribute"
 )
@@ -21,63 +24,109 @@ func (a *Attribute) UnmarshalJSON(b []byte) error {
 	}
 
 	kv := helper{}
-	if err := json.Unmarshal(b, &kv); err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&kv); err != nil {
 		return err
 	}
 
+	value, err := ParseValue(kv.Value.Type, kv.Value.Value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", kv.Key, err)
+	}
+
 	a.Key = attribute.Key(kv.Key)
-	a.Value = ParseValue(kv.Value.Type, kv.Value.Value)
+	a.Value = value
 
 	return nil
 }
 
-func ParseValue(valType string, val any) attribute.Value {
+// ParseValue converts a value decoded from json into an attribute value of the given type.  The
+// json must be decoded with UseNumber, as int64s above 2^53 don't survive being a float64.
+func ParseValue(valType string, val any) (attribute.Value, error) {
 
 	switch valType {
 	case "BOOL":
-		return attribute.BoolValue(val.(bool))
+		b, err := asBool(val)
+		return attribute.BoolValue(b), err
 
 	case "BOOLSLICE":
-		sl := val.([]any)
-		bools := make([]bool, len(sl))
-		for i, v := range sl {
-			bools[i] = v.(bool)
-		}
-		return attribute.BoolSliceValue(bools)
+		bools, err := asSlice(val, asBool)
+		return attribute.BoolSliceValue(bools), err
 
 	case "INT64":
-		return attribute.Int64Value(int64(val.(float64)))
+		i, err := asInt64(val)
+		return attribute.Int64Value(i), err
 
 	case "INT64SLICE":
-		sl := val.([]any)
-		ints := make([]int64, len(sl))
-		for i, v := range sl {
-			ints[i] = int64(v.(float64))
-		}
-		return attribute.Int64SliceValue(ints)
+		ints, err := asSlice(val, asInt64)
+		return attribute.Int64SliceValue(ints), err
 
 	case "FLOAT64":
-		return attribute.Float64Value(val.(float64))
+		f, err := asFloat64(val)
+		return attribute.Float64Value(f), err
 
 	case "FLOAT64SLICE":
-		sl := val.([]any)
-		floats := make([]float64, len(sl))
-		for i, v := range sl {
-			floats[i] = v.(float64)
-		}
-		return attribute.Float64SliceValue(floats)
+		floats, err := asSlice(val, asFloat64)
+		return attribute.Float64SliceValue(floats), err
 
 	case "STRING":
-		return attribute.StringValue(val.(string))
+		str, err := asString(val)
+		return attribute.StringValue(str), err
 
 	case "STRINGSLICE":
-		sl := val.([]any)
-		strings := make([]string, len(sl))
-		for i, v := range sl {
-			strings[i] = v.(string)
-		}
-		return attribute.StringSliceValue(strings)
+		strs, err := asSlice(val, asString)
+		return attribute.StringSliceValue(strs), err
+	}
+
+	return attribute.Value{}, nil
+}
+
+func asBool(val any) (bool, error) {
+	b, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected a bool, got %T", val)
+	}
+	return b, nil
+}
+
+func asInt64(val any) (int64, error) {
+	n, ok := val.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected a number, got %T", val)
+	}
+	return strconv.ParseInt(n.String(), 10, 64)
+}
+
+func asFloat64(val any) (float64, error) {
+	n, ok := val.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected a number, got %T", val)
+	}
+	return strconv.ParseFloat(n.String(), 64)
+}
+
+func asString(val any) (string, error) {
+	str, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %T", val)
+	}
+	return str, nil
+}
+
+func asSlice[T any](val any, element func(any) (T, error)) ([]T, error) {
+	sl, ok := val.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an array, got %T", val)
 	}
 
-	return attribute.Value{}
+	values := make([]T, len(sl))
+	for i, v := range sl {
+		parsed, err := element(v)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		values[i] = parsed
+	}
+	return values, nil
 }
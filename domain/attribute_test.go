@@ -2,6 +2,7 @@ package domain
 
 import (
 	"encoding/json"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -103,3 +104,44 @@ func TestDeserialization(t *testing.T) {
 		})
 	}
 }
+
+func TestDeserializationErrors(t *testing.T) {
+	cases := map[string]string{
+		"bool":          `{"Key": "k", "Value": {"Type": "BOOL", "Value": "true"}}`,
+		"bool slice":    `{"Key": "k", "Value": {"Type": "BOOLSLICE", "Value": [true, 1]}}`,
+		"int":           `{"Key": "k", "Value": {"Type": "INT64", "Value": "12"}}`,
+		"fractional":    `{"Key": "k", "Value": {"Type": "INT64", "Value": 1.5}}`,
+		"int slice":     `{"Key": "k", "Value": {"Type": "INT64SLICE", "Value": 12}}`,
+		"float":         `{"Key": "k", "Value": {"Type": "FLOAT64", "Value": null}}`,
+		"float slice":   `{"Key": "k", "Value": {"Type": "FLOAT64SLICE", "Value": [1.5, "NaN"]}}`,
+		"string":        `{"Key": "k", "Value": {"Type": "STRING", "Value": 12}}`,
+		"string slice":  `{"Key": "k", "Value": {"Type": "STRINGSLICE", "Value": {"a": "b"}}}`,
+		"missing value": `{"Key": "k", "Value": {"Type": "STRING"}}`,
+	}
+
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			var attr Attribute
+			require.ErrorContains(t, json.Unmarshal([]byte(content), &attr), "k: ")
+		})
+	}
+}
+
+func TestRoundTripLargeIntegers(t *testing.T) {
+	// above 2^53, where a float64 can no longer represent every integer
+	for _, kv := range []attribute.KeyValue{
+		attribute.Int64("big", 9007199254740993),
+		attribute.Int64("max", math.MaxInt64),
+		attribute.Int64("min", math.MinInt64),
+		attribute.Int64Slice("slice", []int64{9007199254740993, -9007199254740993}),
+	} {
+		t.Run(string(kv.Key), func(t *testing.T) {
+			b, err := json.Marshal(Attribute{KeyValue: kv})
+			require.NoError(t, err)
+
+			var attr Attribute
+			require.NoError(t, json.Unmarshal(b, &attr))
+			require.Equal(t, kv, attr.KeyValue)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Event is the stored form of sdktrace.Event.  The attributes are wrapped so their values
+// survive a json round trip, which sdktrace.Event's attribute.KeyValues don't.
+type Event struct {
+	Name                  string
+	Attributes            []Attribute
+	DroppedAttributeCount int
+	Time                  time.Time
+}
+
+func NewEvent(e sdktrace.Event) Event {
+	attrs := make([]Attribute, len(e.Attributes))
+	for i, attr := range e.Attributes {
+		attrs[i] = Attribute{KeyValue: attr}
+	}
+
+	return Event{
+		Name:                  e.Name,
+		Attributes:            attrs,
+		DroppedAttributeCount: e.DroppedAttributeCount,
+		Time:                  e.Time,
+	}
+}
+
+func NewEvents(events []sdktrace.Event) []Event {
+	converted := make([]Event, len(events))
+	for i, e := range events {
+		converted[i] = NewEvent(e)
+	}
+	return converted
+}
+
+// KeyValues unwraps the event's attributes
+func (e Event) KeyValues() []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(e.Attributes))
+	for i, attr := range e.Attributes {
+		kvs[i] = attr.KeyValue
+	}
+	return kvs
+}
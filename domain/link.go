@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Link is the stored form of sdktrace.Link, with the span context and attributes wrapped so
+// they survive a json round trip
+type Link struct {
+	SpanContext           SpanContext
+	Attributes            []Attribute
+	DroppedAttributeCount int
+}
+
+func NewLink(l sdktrace.Link) Link {
+	attrs := make([]Attribute, len(l.Attributes))
+	for i, attr := range l.Attributes {
+		attrs[i] = Attribute{KeyValue: attr}
+	}
+
+	return Link{
+		SpanContext:           SpanContext{SpanContext: l.SpanContext},
+		Attributes:            attrs,
+		DroppedAttributeCount: l.DroppedAttributeCount,
+	}
+}
+
+func NewLinks(links []sdktrace.Link) []Link {
+	converted := make([]Link, len(links))
+	for i, l := range links {
+		converted[i] = NewLink(l)
+	}
+	return converted
+}
+
+// KeyValues unwraps the link's attributes
+func (l Link) KeyValues() []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(l.Attributes))
+	for i, attr := range l.Attributes {
+		kvs[i] = attr.KeyValue
+	}
+	return kvs
+}
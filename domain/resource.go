@@ -1,29 +1,66 @@
 package domain
 
 import (
+	"bytes"
 	"encoding/json"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
+// Resource is stored with its schema url and wrapped attributes, so it survives a json round
+// trip.  A resource without attributes is still a resource rather than null.
 type Resource struct {
 	*resource.Resource
 }
 
+type resourceJSON struct {
+	SchemaURL  string
+	Attributes []Attribute
+}
+
+func (r Resource) MarshalJSON() ([]byte, error) {
+	stored := resourceJSON{Attributes: []Attribute{}}
+	if r.Resource != nil {
+		stored.SchemaURL = r.SchemaURL()
+		stored.Attributes = wrapAttributes(r.Attributes())
+	}
+	return json.Marshal(stored)
+}
+
 func (r *Resource) UnmarshalJSON(b []byte) error {
 
-	var read []Attribute
+	// resources used to be stored as just their attributes
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		var read []Attribute
+		if err := json.Unmarshal(b, &read); err != nil {
+			return err
+		}
+		r.Resource = resource.NewSchemaless(unwrapAttributes(read)...)
+		return nil
+	}
+
+	var read resourceJSON
 	if err := json.Unmarshal(b, &read); err != nil {
 		return err
 	}
 
-	attrs := make([]attribute.KeyValue, len(read))
-	for i, attr := range read {
-		attrs[i] = attr.KeyValue
+	r.Resource = resource.NewWithAttributes(read.SchemaURL, unwrapAttributes(read.Attributes)...)
+	return nil
+}
+
+func wrapAttributes(kvs []attribute.KeyValue) []Attribute {
+	attrs := make([]Attribute, len(kvs))
+	for i, kv := range kvs {
+		attrs[i] = Attribute{KeyValue: kv}
 	}
+	return attrs
+}
 
-	r.Resource = resource.NewWithAttributes(semconv.SchemaURL, attrs...)
-	return nil
+func unwrapAttributes(attrs []Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		kvs[i] = attr.KeyValue
+	}
+	return kvs
 }
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"encoding/json"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+)
+
+// Scope is the stored form of instrumentation.Scope, whose attribute set can't be written as
+// json
+type Scope struct {
+	instrumentation.Scope
+}
+
+type scopeJSON struct {
+	Name       string
+	Version    string
+	SchemaURL  string
+	Attributes []Attribute
+}
+
+func (s Scope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(scopeJSON{
+		Name:       s.Name,
+		Version:    s.Version,
+		SchemaURL:  s.SchemaURL,
+		Attributes: wrapAttributes(s.Attributes.ToSlice()),
+	})
+}
+
+func (s *Scope) UnmarshalJSON(b []byte) error {
+	var read scopeJSON
+	if err := json.Unmarshal(b, &read); err != nil {
+		return err
+	}
+
+	s.Scope = instrumentation.Scope{
+		Name:       read.Name,
+		Version:    read.Version,
+		SchemaURL:  read.SchemaURL,
+		Attributes: attribute.NewSet(unwrapAttributes(read.Attributes)...),
+	}
+	return nil
+}
@@ -1,14 +1,16 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
-	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/attribute"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type Spans []Spans
+// Spans is a group of spans, such as the spans of a trace
+type Spans []*Span
 
 type Span struct {
 	Name                 string
@@ -18,13 +20,172 @@ type Span struct {
 	StartTime            time.Time
 	EndTime              time.Time
 	Attributes           []Attribute
-	Events               []sdktrace.Event
-	Links                []sdktrace.Link
+	Events               []Event
+	Links                []Link
 	Status               sdktrace.Status
 	DroppedAttributes    int
 	DroppedEvents        int
 	DroppedLinks         int
 	ChildSpanCount       int
 	Resource             *Resource
-	InstrumentationScope instrumentation.Scope
+	InstrumentationScope Scope
+}
+
+// Intrinsic fields are the properties of the span itself rather than its attributes.  They are
+// indexed separately from attributes, so can't collide with an attribute of the same name.
+const (
+	NameField          = "name"
+	DurationField      = "duration_ms"
+	StatusField        = "status"
+	StatusMessageField = "status.message"
+	KindField          = "kind"
+	ScopeNameField     = "scope.name"
+	ScopeVersionField  = "scope.version"
+	RootField          = "root"
+)
+
+var intrinsicFields = map[string]bool{
+	NameField:          true,
+	DurationField:      true,
+	StatusField:        true,
+	StatusMessageField: true,
+	KindField:          true,
+	ScopeNameField:     true,
+	ScopeVersionField:  true,
+	RootField:          true,
+}
+
+func IsIntrinsic(field string) bool {
+	return intrinsicFields[field]
+}
+
+// EventNameField is the name of any of the span's events, such as `exception`.  Like the span's
+// intrinsics, it is indexed apart from the event attributes.
+const EventNameField = "event.name"
+
+func IsEventIntrinsic(field string) bool {
+	return field == EventNameField
+}
+
+// Intrinsics lists the span's intrinsic fields.  The status message and scope are left out
+// when they are empty.
+func (s *Span) Intrinsics() []attribute.KeyValue {
+	intrinsics := []attribute.KeyValue{
+		attribute.String(NameField, s.Name),
+		attribute.Float64(DurationField, float64(s.EndTime.Sub(s.StartTime))/float64(time.Millisecond)),
+		attribute.String(StatusField, strings.ToLower(s.Status.Code.String())),
+		attribute.String(KindField, s.SpanKind.String()),
+		attribute.Bool(RootField, !s.Parent.IsValid()),
+	}
+
+	if s.Status.Description != "" {
+		intrinsics = append(intrinsics, attribute.String(StatusMessageField, s.Status.Description))
+	}
+	if s.InstrumentationScope.Name != "" {
+		intrinsics = append(intrinsics, attribute.String(ScopeNameField, s.InstrumentationScope.Name))
+	}
+	if s.InstrumentationScope.Version != "" {
+		intrinsics = append(intrinsics, attribute.String(ScopeVersionField, s.InstrumentationScope.Version))
+	}
+
+	return intrinsics
+}
+
+// Attribute scopes are written as a prefix on a field, such as `resource.service.name`.  Unscoped
+// fields match either the span's or the resource's attributes.
+const (
+	SpanScope     = "span"
+	ResourceScope = "resource"
+	EventScope    = "event"
+	LinkScope     = "link"
+)
+
+var scopes = []string{SpanScope, ResourceScope, EventScope, LinkScope}
+
+// SplitScope separates the scope prefix from a field.  The scope is empty for unscoped fields
+// and intrinsics.
+func SplitScope(field string) (string, string) {
+	if IsIntrinsic(field) || IsEventIntrinsic(field) {
+		return "", field
+	}
+
+	for _, scope := range scopes {
+		if key, found := strings.CutPrefix(field, scope+"."); found && key != "" {
+			return scope, key
+		}
+	}
+
+	return "", field
+}
+
+// Value finds a field on the span.  Intrinsic fields take precedence over attributes, and
+// unscoped fields check the span's attributes before its resource's.  Event and link fields
+// are the first event or link with the attribute, and the event name is the first event's.
+func (s *Span) Value(field string) (attribute.Value, bool) {
+	if IsIntrinsic(field) {
+		return find(s.Intrinsics(), field)
+	}
+
+	if field == EventNameField {
+		if len(s.Events) == 0 {
+			return attribute.Value{}, false
+		}
+		return attribute.StringValue(s.Events[0].Name), true
+	}
+
+	scope, key := SplitScope(field)
+
+	switch scope {
+	case SpanScope:
+		return s.spanAttribute(key)
+
+	case ResourceScope:
+		return s.resourceAttribute(key)
+
+	case EventScope:
+		for _, event := range s.Events {
+			if value, found := find(event.KeyValues(), key); found {
+				return value, true
+			}
+		}
+		return attribute.Value{}, false
+
+	case LinkScope:
+		for _, link := range s.Links {
+			if value, found := find(link.KeyValues(), key); found {
+				return value, true
+			}
+		}
+		return attribute.Value{}, false
+	}
+
+	if value, found := s.spanAttribute(key); found {
+		return value, true
+	}
+	return s.resourceAttribute(key)
+}
+
+func (s *Span) spanAttribute(key string) (attribute.Value, bool) {
+	for _, attr := range s.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func (s *Span) resourceAttribute(key string) (attribute.Value, bool) {
+	if s.Resource == nil || s.Resource.Resource == nil {
+		return attribute.Value{}, false
+	}
+	return s.Resource.Set().Value(attribute.Key(key))
+}
+
+func find(attrs []attribute.KeyValue, key string) (attribute.Value, bool) {
+	for _, attr := range attrs {
+		if string(attr.Key) == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
 }
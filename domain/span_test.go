@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanValue(t *testing.T) {
+	start := time.Now()
+	span := &Span{
+		Name:       "GET /",
+		StartTime:  start,
+		EndTime:    start.Add(1500 * time.Microsecond),
+		Attributes: []Attribute{{attribute.Int("http.status_code", 200)}, {attribute.String("service.name", "override")}},
+		Resource:   &Resource{resource.NewSchemaless(attribute.String("service.name", "api"), attribute.String("host", "a"))},
+	}
+
+	cases := []struct {
+		Key      string
+		Expected attribute.Value
+	}{
+		{"name", attribute.StringValue("GET /")},
+		{"duration_ms", attribute.Float64Value(1.5)},
+		{"http.status_code", attribute.IntValue(200)},
+		{"service.name", attribute.StringValue("override")},
+		{"host", attribute.StringValue("a")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Key, func(t *testing.T) {
+			value, found := span.Value(tc.Key)
+			require.True(t, found)
+			require.Equal(t, tc.Expected, value)
+		})
+	}
+
+	_, found := span.Value("missing")
+	require.False(t, found)
+
+	_, found = (&Span{}).Value("host")
+	require.False(t, found)
+}
+
+func TestSpanIntrinsics(t *testing.T) {
+	start := time.Now()
+	span := &Span{
+		Name:      "GET /",
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Millisecond),
+		SpanKind:  trace.SpanKindServer,
+		Status:    sdktrace.Status{Code: codes.Error, Description: "timeout"},
+		Attributes: []Attribute{
+			{attribute.String("kind", "attribute")},
+		},
+		InstrumentationScope: Scope{instrumentation.Scope{Name: "net/http"}},
+	}
+
+	require.Equal(t, []attribute.KeyValue{
+		attribute.String(NameField, "GET /"),
+		attribute.Float64(DurationField, 2),
+		attribute.String(StatusField, "error"),
+		attribute.String(KindField, "server"),
+		attribute.Bool(RootField, true),
+		attribute.String(StatusMessageField, "timeout"),
+		attribute.String(ScopeNameField, "net/http"),
+	}, span.Intrinsics())
+
+	// intrinsics take precedence over attributes
+	value, found := span.Value(KindField)
+	require.True(t, found)
+	require.Equal(t, "server", value.AsString())
+}
+
+func TestSplitScope(t *testing.T) {
+	cases := []struct {
+		Field string
+		Scope string
+		Key   string
+	}{
+		{"http.method", "", "http.method"},
+		{"span.http.method", SpanScope, "http.method"},
+		{"resource.service.name", ResourceScope, "service.name"},
+		{"event.exception.type", EventScope, "exception.type"},
+		{"link.messaging.operation", LinkScope, "messaging.operation"},
+		{"span.status", SpanScope, "status"},
+		{"event.name", "", "event.name"},
+		{"scope.name", "", "scope.name"},
+		{"span", "", "span"},
+		{"span.", "", "span."},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Field, func(t *testing.T) {
+			scope, key := SplitScope(tc.Field)
+			require.Equal(t, tc.Scope, scope)
+			require.Equal(t, tc.Key, key)
+		})
+	}
+}
+
+func TestSpanScopedValue(t *testing.T) {
+	span := &Span{
+		Attributes: []Attribute{{attribute.String("service.name", "span")}, {attribute.Int("status", 200)}},
+		Resource:   &Resource{resource.NewSchemaless(attribute.String("service.name", "resource"))},
+		Events: []Event{
+			{Name: "retry"},
+			{Name: "exception", Attributes: []Attribute{{attribute.String("exception.type", "Timeout")}}},
+		},
+		Links: []Link{{Attributes: []Attribute{{attribute.Bool("follows", true)}}}},
+	}
+
+	cases := []struct {
+		Field    string
+		Expected attribute.Value
+	}{
+		{"service.name", attribute.StringValue("span")},
+		{"span.service.name", attribute.StringValue("span")},
+		{"resource.service.name", attribute.StringValue("resource")},
+		{"event.exception.type", attribute.StringValue("Timeout")},
+		{"event.name", attribute.StringValue("retry")},
+		{"link.follows", attribute.BoolValue(true)},
+		{"span.status", attribute.IntValue(200)},
+		{"status", attribute.StringValue("unset")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Field, func(t *testing.T) {
+			value, found := span.Value(tc.Field)
+			require.True(t, found)
+			require.Equal(t, tc.Expected, value)
+		})
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	now := time.Now().UTC()
+	span := Span{
+		Events: NewEvents([]sdktrace.Event{{
+			Name:                  "exception",
+			Attributes:            []attribute.KeyValue{attribute.String("exception.type", "TimeoutError"), attribute.Int("attempt", 3)},
+			DroppedAttributeCount: 1,
+			Time:                  now,
+		}}),
+	}
+
+	content, err := json.Marshal(span)
+	require.NoError(t, err)
+
+	read := Span{}
+	require.NoError(t, json.Unmarshal(content, &read))
+
+	require.Equal(t, span.Events, read.Events)
+	require.Equal(t, []attribute.KeyValue{attribute.String("exception.type", "TimeoutError"), attribute.Int("attempt", 3)}, read.Events[0].KeyValues())
+}
@@ -0,0 +1,227 @@
+package domain
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Node is a span in a trace's tree, with its children ordered by start time
+type Node struct {
+	Span     *Span
+	Parent   *Node
+	Children []*Node
+	Depth    int
+}
+
+// Trace links the spans of a trace into a tree by their parent span ids.  Roots are the spans
+// without a parent, and orphans are spans whose parent isn't in the trace, which happens when
+// the parent hasn't been received yet or was dropped.  Both are ordered by start time.
+type Trace struct {
+	Roots   []*Node
+	Orphans []*Node
+
+	nodes map[trace.SpanID]*Node
+}
+
+// NewTrace builds the tree for a trace's spans, which can be in any order.  A span id seen more
+// than once keeps only its first span.
+func NewTrace(spans Spans) *Trace {
+	t := &Trace{nodes: make(map[trace.SpanID]*Node, len(spans))}
+
+	nodes := make([]*Node, 0, len(spans))
+	for _, span := range spans {
+		sid := span.SpanContext.SpanID()
+		if _, found := t.nodes[sid]; found {
+			continue
+		}
+
+		node := &Node{Span: span}
+		t.nodes[sid] = node
+		nodes = append(nodes, node)
+	}
+
+	for _, node := range nodes {
+		if !node.Span.Parent.IsValid() {
+			t.Roots = append(t.Roots, node)
+			continue
+		}
+
+		parent, found := t.nodes[node.Span.Parent.SpanID()]
+		if !found {
+			t.Orphans = append(t.Orphans, node)
+			continue
+		}
+
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+
+	sortByStart(t.Roots)
+	sortByStart(t.Orphans)
+
+	reached := make(map[*Node]bool, len(nodes))
+	for _, node := range slices.Concat(t.Roots, t.Orphans) {
+		setDepth(node, 0, reached)
+	}
+
+	// spans whose parents form a cycle can't be reached from a root or an orphan, so the cycle
+	// is broken and they become orphans
+	for _, node := range nodes {
+		if reached[node] {
+			continue
+		}
+
+		parent := node.Parent
+		parent.Children = slices.DeleteFunc(parent.Children, func(n *Node) bool { return n == node })
+		node.Parent = nil
+
+		t.Orphans = append(t.Orphans, node)
+		setDepth(node, 0, reached)
+	}
+
+	return t
+}
+
+func setDepth(node *Node, depth int, reached map[*Node]bool) {
+	reached[node] = true
+	node.Depth = depth
+
+	sortByStart(node.Children)
+	for _, child := range node.Children {
+		setDepth(child, depth+1, reached)
+	}
+}
+
+func sortByStart(nodes []*Node) {
+	slices.SortStableFunc(nodes, func(a, b *Node) int {
+		return a.Span.StartTime.Compare(b.Span.StartTime)
+	})
+}
+
+// Find looks up a span's node by its id
+func (t *Trace) Find(sid trace.SpanID) (*Node, bool) {
+	node, found := t.nodes[sid]
+	return node, found
+}
+
+// Len is the number of spans in the tree
+func (t *Trace) Len() int {
+	return len(t.nodes)
+}
+
+// MissingParents lists the parent span ids of the orphans which aren't in the trace
+func (t *Trace) MissingParents() []trace.SpanID {
+	missing := []trace.SpanID{}
+	for _, node := range t.Orphans {
+		sid := node.Span.Parent.SpanID()
+		if _, found := t.nodes[sid]; !found && !slices.Contains(missing, sid) {
+			missing = append(missing, sid)
+		}
+	}
+	return missing
+}
+
+// Walk visits the spans depth first, with each span before its children.  The roots are
+// visited first, then the orphans.
+func (t *Trace) Walk() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		for _, node := range slices.Concat(t.Roots, t.Orphans) {
+			if !node.walk(yield) {
+				return
+			}
+		}
+	}
+}
+
+func (n *Node) walk(yield func(*Node) bool) bool {
+	if !yield(n) {
+		return false
+	}
+
+	for _, child := range n.Children {
+		if !child.walk(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Start is the start of the earliest span, or the zero time when there are no spans
+func (t *Trace) Start() time.Time {
+	start := time.Time{}
+	for _, node := range t.nodes {
+		if start.IsZero() || node.Span.StartTime.Before(start) {
+			start = node.Span.StartTime
+		}
+	}
+	return start
+}
+
+// End is the end of the latest span, or the zero time when there are no spans
+func (t *Trace) End() time.Time {
+	end := time.Time{}
+	for _, node := range t.nodes {
+		if node.Span.EndTime.After(end) {
+			end = node.Span.EndTime
+		}
+	}
+	return end
+}
+
+// Duration is the time from the earliest span starting to the latest span ending, which
+// includes any orphans
+func (t *Trace) Duration() time.Duration {
+	return t.End().Sub(t.Start())
+}
+
+// SelfTime is the time spent in the span but not in any of its children.  Overlapping children
+// are only counted once, and the parts of children outside the span, such as asynchronous work
+// which outlives it, are ignored.
+func (n *Node) SelfTime() time.Duration {
+	start, end := n.Span.StartTime, n.Span.EndTime
+	if !end.After(start) {
+		return 0
+	}
+
+	type interval struct{ start, end time.Time }
+	intervals := make([]interval, 0, len(n.Children))
+	for _, child := range n.Children {
+		s, e := maxTime(child.Span.StartTime, start), minTime(child.Span.EndTime, end)
+		if e.After(s) {
+			intervals = append(intervals, interval{s, e})
+		}
+	}
+
+	slices.SortFunc(intervals, func(a, b interval) int {
+		return cmp.Or(a.start.Compare(b.start), a.end.Compare(b.end))
+	})
+
+	self := end.Sub(start)
+	covered := start
+	for _, i := range intervals {
+		if i.end.After(covered) {
+			self -= i.end.Sub(maxTime(i.start, covered))
+			covered = i.end
+		}
+	}
+
+	return self
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
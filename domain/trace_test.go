@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var traceStart = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// treeSpan creates a span with ids derived from small numbers, where a parent of 0 means none,
+// starting and ending at offsets in milliseconds from traceStart
+func treeSpan(name string, id, parent byte, start, end int) *Span {
+	tid := trace.TraceID{1}
+	span := &Span{
+		Name:        name,
+		SpanContext: SpanContext{trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{id}})},
+		StartTime:   traceStart.Add(time.Duration(start) * time.Millisecond),
+		EndTime:     traceStart.Add(time.Duration(end) * time.Millisecond),
+	}
+	if parent != 0 {
+		span.Parent = SpanContext{trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{parent}})}
+	}
+	return span
+}
+
+func names(nodes []*Node) []string {
+	n := make([]string, len(nodes))
+	for i, node := range nodes {
+		n[i] = node.Span.Name
+	}
+	return n
+}
+
+func TestTrace(t *testing.T) {
+	tr := NewTrace(Spans{
+		treeSpan("query", 3, 2, 20, 60),
+		treeSpan("orphan", 6, 9, 150, 250),
+		treeSpan("render", 4, 1, 70, 90),
+		treeSpan("root", 1, 0, 0, 100),
+		treeSpan("handler", 2, 1, 10, 80),
+		treeSpan("cache", 5, 2, 40, 70),
+		treeSpan("duplicate", 2, 0, 0, 1),
+	})
+
+	t.Run("structure", func(t *testing.T) {
+		require.Equal(t, 6, tr.Len())
+		require.Equal(t, []string{"root"}, names(tr.Roots))
+		require.Equal(t, []string{"orphan"}, names(tr.Orphans))
+		require.Equal(t, []trace.SpanID{{9}}, tr.MissingParents())
+
+		handler, found := tr.Find(trace.SpanID{2})
+		require.True(t, found)
+		require.Equal(t, "root", handler.Parent.Span.Name)
+		require.Equal(t, []string{"query", "cache"}, names(handler.Children))
+		require.Equal(t, 1, handler.Depth)
+	})
+
+	t.Run("depth first", func(t *testing.T) {
+		walked := slices.Collect(tr.Walk())
+		require.Equal(t, []string{"root", "handler", "query", "cache", "render", "orphan"}, names(walked))
+
+		depths := []int{}
+		for _, node := range walked {
+			depths = append(depths, node.Depth)
+		}
+		require.Equal(t, []int{0, 1, 2, 2, 1, 0}, depths)
+	})
+
+	t.Run("duration", func(t *testing.T) {
+		require.Equal(t, traceStart, tr.Start())
+		require.Equal(t, 250*time.Millisecond, tr.Duration())
+	})
+
+	t.Run("self time", func(t *testing.T) {
+		cases := map[trace.SpanID]time.Duration{
+			// handler and render overlap between 70 and 80
+			{1}: 100*time.Millisecond - 80*time.Millisecond,
+			// query and cache overlap between 40 and 60
+			{2}: 70*time.Millisecond - 50*time.Millisecond,
+			{3}: 40 * time.Millisecond,
+			{4}: 20 * time.Millisecond,
+		}
+
+		for sid, expected := range cases {
+			node, found := tr.Find(sid)
+			require.True(t, found)
+			require.Equal(t, expected, node.SelfTime(), node.Span.Name)
+		}
+	})
+}
+
+func TestTraceCycle(t *testing.T) {
+	tr := NewTrace(Spans{
+		treeSpan("a", 1, 2, 0, 10),
+		treeSpan("b", 2, 1, 5, 10),
+	})
+
+	require.Empty(t, tr.Roots)
+	require.Len(t, tr.Orphans, 1)
+	require.Len(t, slices.Collect(tr.Walk()), 2)
+	require.Empty(t, tr.MissingParents())
+}
+
+func TestEmptyTrace(t *testing.T) {
+	tr := NewTrace(nil)
+
+	require.Zero(t, tr.Len())
+	require.Zero(t, tr.Duration())
+	require.Empty(t, slices.Collect(tr.Walk()))
+}
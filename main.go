@@ -2,9 +2,14 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"romulus/command"
+	"romulus/command/export"
+	"romulus/command/ingest"
+	"romulus/command/query"
+	"romulus/command/serve"
+	"romulus/command/trace"
 	"romulus/command/version"
-	"os"
 
 	"github.com/hashicorp/cli"
 )
@@ -12,7 +17,12 @@ import (
 func main() {
 
 	commands := map[string]cli.CommandFactory{
-		"version":            command.NewCommand(version.NewVersionCommand()),
+		"export":  command.NewCommand(export.NewExportCommand()),
+		"ingest":  command.NewCommand(ingest.NewIngestCommand()),
+		"query":   command.NewCommand(query.NewQueryCommand()),
+		"serve":   command.NewCommand(serve.NewServeCommand()),
+		"trace":   command.NewCommand(trace.NewTraceCommand()),
+		"version": command.NewCommand(version.NewVersionCommand()),
 	}
 
 	cli := &cli.CLI{
@@ -0,0 +1,419 @@
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"romulus/domain"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// ToSpans flattens the resource and scope hierarchy of an OTLP payload into individual spans.
+// Anything which made it to an exporter was sampled, so all span contexts are marked as such.
+// Spans which cannot be stored (for example, missing a trace or span id) are skipped, counted
+// in rejected, and described by the returned error.  The valid spans are always returned.  A
+// resource or scope which cannot be stored rejects all of its spans.
+func ToSpans(resourceSpans []*tracepb.ResourceSpans) ([]domain.Span, int, error) {
+	spans := []domain.Span{}
+	rejected := 0
+	errs := []error{}
+
+	for _, rs := range resourceSpans {
+		res, err := toResource(rs.GetResource(), rs.GetSchemaUrl())
+		if err != nil {
+			for _, ss := range rs.GetScopeSpans() {
+				rejected += len(ss.GetSpans())
+			}
+			errs = append(errs, fmt.Errorf("resource: %w", err))
+			continue
+		}
+
+		for _, ss := range rs.GetScopeSpans() {
+			scope, err := toScope(ss.GetScope(), ss.GetSchemaUrl())
+			if err != nil {
+				rejected += len(ss.GetSpans())
+				errs = append(errs, fmt.Errorf("scope %q: %w", ss.GetScope().GetName(), err))
+				continue
+			}
+
+			for _, ps := range ss.GetSpans() {
+				span, err := toSpan(ps)
+				if err != nil {
+					rejected++
+					errs = append(errs, err)
+					continue
+				}
+
+				span.Resource = res
+				span.InstrumentationScope = scope
+				spans = append(spans, span)
+			}
+		}
+	}
+
+	return spans, rejected, errors.Join(errs...)
+}
+
+func toSpan(ps *tracepb.Span) (domain.Span, error) {
+	tid, err := toTraceID(ps.GetTraceId())
+	if err != nil {
+		return domain.Span{}, fmt.Errorf("span %q: %w", ps.GetName(), err)
+	}
+
+	sid, err := toSpanID(ps.GetSpanId())
+	if err != nil {
+		return domain.Span{}, fmt.Errorf("span %q: %w", ps.GetName(), err)
+	}
+
+	parent := trace.SpanContext{}
+	if len(ps.GetParentSpanId()) > 0 {
+		psid, err := toSpanID(ps.GetParentSpanId())
+		if err != nil {
+			return domain.Span{}, fmt.Errorf("span %q parent: %w", ps.GetName(), err)
+		}
+
+		parent = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    tid,
+			SpanID:     psid,
+			TraceFlags: trace.FlagsSampled,
+		})
+	}
+
+	state, err := trace.ParseTraceState(ps.GetTraceState())
+	if err != nil {
+		return domain.Span{}, fmt.Errorf("span %q: %w", ps.GetName(), err)
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+	})
+
+	events := make([]domain.Event, len(ps.GetEvents()))
+	for i, e := range ps.GetEvents() {
+		attrs, err := toAttributes(e.GetAttributes())
+		if err != nil {
+			return domain.Span{}, fmt.Errorf("span %q event %q: %w", ps.GetName(), e.GetName(), err)
+		}
+
+		events[i] = domain.NewEvent(sdktrace.Event{
+			Name:                  e.GetName(),
+			Attributes:            attrs,
+			DroppedAttributeCount: int(e.GetDroppedAttributesCount()),
+			Time:                  toTime(e.GetTimeUnixNano()),
+		})
+	}
+
+	links := make([]domain.Link, 0, len(ps.GetLinks()))
+	for _, l := range ps.GetLinks() {
+		link, err := toLink(l)
+		if err != nil {
+			return domain.Span{}, fmt.Errorf("span %q link: %w", ps.GetName(), err)
+		}
+		links = append(links, link)
+	}
+
+	attrs, err := toAttributes(ps.GetAttributes())
+	if err != nil {
+		return domain.Span{}, fmt.Errorf("span %q: %w", ps.GetName(), err)
+	}
+	wrapped := make([]domain.Attribute, len(attrs))
+	for i, attr := range attrs {
+		wrapped[i] = domain.Attribute{KeyValue: attr}
+	}
+
+	return domain.Span{
+		Name:              ps.GetName(),
+		SpanContext:       domain.SpanContext{SpanContext: sc},
+		Parent:            domain.SpanContext{SpanContext: parent},
+		SpanKind:          trace.SpanKind(ps.GetKind()),
+		StartTime:         toTime(ps.GetStartTimeUnixNano()),
+		EndTime:           toTime(ps.GetEndTimeUnixNano()),
+		Attributes:        wrapped,
+		Events:            events,
+		Links:             links,
+		Status:            toStatus(ps.GetStatus()),
+		DroppedAttributes: int(ps.GetDroppedAttributesCount()),
+		DroppedEvents:     int(ps.GetDroppedEventsCount()),
+		DroppedLinks:      int(ps.GetDroppedLinksCount()),
+	}, nil
+}
+
+func toLink(l *tracepb.Span_Link) (domain.Link, error) {
+	tid, err := toTraceID(l.GetTraceId())
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	sid, err := toSpanID(l.GetSpanId())
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	state, err := trace.ParseTraceState(l.GetTraceState())
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	attrs, err := toAttributes(l.GetAttributes())
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	return domain.NewLink(sdktrace.Link{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    tid,
+			SpanID:     sid,
+			TraceFlags: trace.FlagsSampled,
+			TraceState: state,
+		}),
+		Attributes:            attrs,
+		DroppedAttributeCount: int(l.GetDroppedAttributesCount()),
+	}), nil
+}
+
+func toResource(r *resourcepb.Resource, schemaURL string) (*domain.Resource, error) {
+	attrs, err := toAttributes(r.GetAttributes())
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.Resource{
+		Resource: resource.NewWithAttributes(schemaURL, attrs...),
+	}, nil
+}
+
+func toScope(s *commonpb.InstrumentationScope, schemaURL string) (domain.Scope, error) {
+	attrs, err := toAttributes(s.GetAttributes())
+	if err != nil {
+		return domain.Scope{}, err
+	}
+
+	return domain.Scope{Scope: instrumentation.Scope{
+		Name:       s.GetName(),
+		Version:    s.GetVersion(),
+		SchemaURL:  schemaURL,
+		Attributes: attribute.NewSet(attrs...),
+	}}, nil
+}
+
+// OTLP status codes are ordered differently to the otel codes package
+func toStatus(s *tracepb.Status) sdktrace.Status {
+	status := sdktrace.Status{Description: s.GetMessage()}
+
+	switch s.GetCode() {
+	case tracepb.Status_STATUS_CODE_OK:
+		status.Code = codes.Ok
+	case tracepb.Status_STATUS_CODE_ERROR:
+		status.Code = codes.Error
+	default:
+		status.Code = codes.Unset
+	}
+
+	return status
+}
+
+func toTraceID(b []byte) (trace.TraceID, error) {
+	tid := trace.TraceID{}
+	if len(b) != len(tid) {
+		return tid, fmt.Errorf("trace id must be %d bytes, got %d", len(tid), len(b))
+	}
+
+	copy(tid[:], b)
+	if !tid.IsValid() {
+		return tid, errors.New("trace id must not be all zeros")
+	}
+
+	return tid, nil
+}
+
+func toSpanID(b []byte) (trace.SpanID, error) {
+	sid := trace.SpanID{}
+	if len(b) != len(sid) {
+		return sid, fmt.Errorf("span id must be %d bytes, got %d", len(sid), len(b))
+	}
+
+	copy(sid[:], b)
+	if !sid.IsValid() {
+		return sid, errors.New("span id must not be all zeros")
+	}
+
+	return sid, nil
+}
+
+func toTime(nanos uint64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(nanos))
+}
+
+// toAttributes converts OTLP attributes, skipping values which have no attribute equivalent.
+// NaN and infinite doubles have no json representation, so can't be stored at all.
+func toAttributes(kvs []*commonpb.KeyValue) ([]attribute.KeyValue, error) {
+	attrs := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		if !finite(kv.GetValue()) {
+			return nil, fmt.Errorf("attribute %q is not a finite number", kv.GetKey())
+		}
+
+		value, ok := toValue(kv.GetValue())
+		if !ok {
+			continue
+		}
+		attrs = append(attrs, attribute.KeyValue{Key: attribute.Key(kv.GetKey()), Value: value})
+	}
+	return attrs, nil
+}
+
+// finite checks every double in the value, including those nested in arrays and maps
+func finite(v *commonpb.AnyValue) bool {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_DoubleValue:
+		return !math.IsNaN(val.DoubleValue) && !math.IsInf(val.DoubleValue, 0)
+	case *commonpb.AnyValue_ArrayValue:
+		for _, item := range val.ArrayValue.GetValues() {
+			if !finite(item) {
+				return false
+			}
+		}
+	case *commonpb.AnyValue_KvlistValue:
+		for _, kv := range val.KvlistValue.GetValues() {
+			if !finite(kv.GetValue()) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// toValue converts an OTLP AnyValue into an attribute value.  Arrays of a single scalar type
+// become slices; anything attribute.Value cannot represent (mixed arrays, maps) is stored as
+// its json string, and bytes are stored base64 encoded.
+func toValue(v *commonpb.AnyValue) (attribute.Value, bool) {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return attribute.StringValue(val.StringValue), true
+	case *commonpb.AnyValue_BoolValue:
+		return attribute.BoolValue(val.BoolValue), true
+	case *commonpb.AnyValue_IntValue:
+		return attribute.Int64Value(val.IntValue), true
+	case *commonpb.AnyValue_DoubleValue:
+		return attribute.Float64Value(val.DoubleValue), true
+	case *commonpb.AnyValue_BytesValue:
+		return attribute.StringValue(base64.StdEncoding.EncodeToString(val.BytesValue)), true
+	case *commonpb.AnyValue_ArrayValue:
+		if slice, ok := toSlice(val.ArrayValue.GetValues()); ok {
+			return slice, true
+		}
+		return attribute.StringValue(toJSON(v)), true
+	case *commonpb.AnyValue_KvlistValue:
+		return attribute.StringValue(toJSON(v)), true
+	}
+
+	return attribute.Value{}, false
+}
+
+func toSlice(values []*commonpb.AnyValue) (attribute.Value, bool) {
+	if len(values) == 0 {
+		return attribute.Value{}, false
+	}
+
+	switch values[0].GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		sl := make([]string, len(values))
+		for i, v := range values {
+			s, ok := v.GetValue().(*commonpb.AnyValue_StringValue)
+			if !ok {
+				return attribute.Value{}, false
+			}
+			sl[i] = s.StringValue
+		}
+		return attribute.StringSliceValue(sl), true
+
+	case *commonpb.AnyValue_BoolValue:
+		sl := make([]bool, len(values))
+		for i, v := range values {
+			b, ok := v.GetValue().(*commonpb.AnyValue_BoolValue)
+			if !ok {
+				return attribute.Value{}, false
+			}
+			sl[i] = b.BoolValue
+		}
+		return attribute.BoolSliceValue(sl), true
+
+	case *commonpb.AnyValue_IntValue:
+		sl := make([]int64, len(values))
+		for i, v := range values {
+			n, ok := v.GetValue().(*commonpb.AnyValue_IntValue)
+			if !ok {
+				return attribute.Value{}, false
+			}
+			sl[i] = n.IntValue
+		}
+		return attribute.Int64SliceValue(sl), true
+
+	case *commonpb.AnyValue_DoubleValue:
+		sl := make([]float64, len(values))
+		for i, v := range values {
+			f, ok := v.GetValue().(*commonpb.AnyValue_DoubleValue)
+			if !ok {
+				return attribute.Value{}, false
+			}
+			sl[i] = f.DoubleValue
+		}
+		return attribute.Float64SliceValue(sl), true
+	}
+
+	return attribute.Value{}, false
+}
+
+func toJSON(v *commonpb.AnyValue) string {
+	b, _ := json.Marshal(plain(v))
+	return string(b)
+}
+
+// plain converts an AnyValue into the equivalent go value, for json encoding
+func plain(v *commonpb.AnyValue) any {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return val.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return val.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return val.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return val.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(val.ArrayValue.GetValues()))
+		for i, e := range val.ArrayValue.GetValues() {
+			values[i] = plain(e)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(val.KvlistValue.GetValues()))
+		for _, kv := range val.KvlistValue.GetValues() {
+			values[kv.GetKey()] = plain(kv.GetValue())
+		}
+		return values
+	}
+
+	return nil
+}
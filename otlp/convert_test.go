@@ -0,0 +1,202 @@
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	traceID  = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	spanID   = []byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	parentID = []byte{0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28}
+)
+
+func stringValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func TestToSpans(t *testing.T) {
+	start := time.Date(2025, 6, 1, 15, 0, 0, 0, time.UTC)
+
+	rs := []*tracepb.ResourceSpans{{
+		Resource: &resourcepb.Resource{
+			Attributes: []*commonpb.KeyValue{stringValue("service.name", "checkout")},
+		},
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Scope: &commonpb.InstrumentationScope{Name: "net/http", Version: "1.2.3"},
+			Spans: []*tracepb.Span{{
+				TraceId:           traceID,
+				SpanId:            spanID,
+				ParentSpanId:      parentID,
+				Name:              "GET /",
+				Kind:              tracepb.Span_SPAN_KIND_SERVER,
+				StartTimeUnixNano: uint64(start.UnixNano()),
+				EndTimeUnixNano:   uint64(start.Add(time.Second).UnixNano()),
+				Attributes: []*commonpb.KeyValue{
+					stringValue("http.route", "/"),
+					{Key: "http.status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 200}}},
+					{Key: "tags", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+						Values: []*commonpb.AnyValue{
+							{Value: &commonpb.AnyValue_StringValue{StringValue: "a"}},
+							{Value: &commonpb.AnyValue_StringValue{StringValue: "b"}},
+						},
+					}}}},
+				},
+				Events: []*tracepb.Span_Event{{
+					Name:         "exception",
+					TimeUnixNano: uint64(start.Add(500 * time.Millisecond).UnixNano()),
+					Attributes:   []*commonpb.KeyValue{stringValue("exception.type", "TimeoutError")},
+				}},
+				Links: []*tracepb.Span_Link{{
+					TraceId:    parentID[:4:4],
+					SpanId:     spanID,
+					Attributes: []*commonpb.KeyValue{stringValue("link.kind", "producer")},
+				}},
+				Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "it broke"},
+			}},
+		}},
+	}}
+
+	t.Run("invalid link rejects the span", func(t *testing.T) {
+		spans, rejected, err := ToSpans(rs)
+		require.Error(t, err)
+		require.Equal(t, 1, rejected)
+		require.Empty(t, spans)
+	})
+
+	rs[0].ScopeSpans[0].Spans[0].Links[0].TraceId = traceID
+
+	t.Run("non finite numbers reject the span", func(t *testing.T) {
+		nan := &commonpb.KeyValue{Key: "ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: math.NaN()}}}
+		inf := &commonpb.KeyValue{Key: "ratios", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+			Values: []*commonpb.AnyValue{{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: math.Inf(1)}}},
+		}}}}
+
+		for _, attr := range []*commonpb.KeyValue{nan, inf} {
+			bad := proto.Clone(rs[0]).(*tracepb.ResourceSpans)
+			bad.ScopeSpans[0].Spans[0].Events[0].Attributes = append(bad.ScopeSpans[0].Spans[0].Events[0].Attributes, attr)
+
+			spans, rejected, err := ToSpans([]*tracepb.ResourceSpans{bad, rs[0]})
+			require.ErrorContains(t, err, "is not a finite number")
+			require.Equal(t, 1, rejected)
+			require.Len(t, spans, 1)
+		}
+	})
+
+	t.Run("non finite resource rejects all its spans", func(t *testing.T) {
+		bad := proto.Clone(rs[0]).(*tracepb.ResourceSpans)
+		bad.Resource.Attributes = append(bad.Resource.Attributes, &commonpb.KeyValue{Key: "load", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: math.Inf(-1)}}})
+
+		spans, rejected, err := ToSpans([]*tracepb.ResourceSpans{bad})
+		require.ErrorContains(t, err, "resource")
+		require.Equal(t, 1, rejected)
+		require.Empty(t, spans)
+	})
+
+	spans, rejected, err := ToSpans(rs)
+	require.NoError(t, err)
+	require.Zero(t, rejected)
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+
+	t.Run("identifiers", func(t *testing.T) {
+		require.Equal(t, "0102030405060708090a0b0c0d0e0f10", span.SpanContext.TraceID().String())
+		require.Equal(t, "1112131415161718", span.SpanContext.SpanID().String())
+		require.Equal(t, "2122232425262728", span.Parent.SpanID().String())
+		require.Equal(t, span.SpanContext.TraceID(), span.Parent.TraceID())
+	})
+
+	t.Run("span fields", func(t *testing.T) {
+		require.Equal(t, "GET /", span.Name)
+		require.Equal(t, trace.SpanKindServer, span.SpanKind)
+		require.True(t, start.Equal(span.StartTime))
+		require.Equal(t, time.Second, span.EndTime.Sub(span.StartTime))
+		require.Equal(t, codes.Error, span.Status.Code)
+		require.Equal(t, "it broke", span.Status.Description)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		require.Len(t, span.Attributes, 3)
+		require.Equal(t, attribute.String("http.route", "/"), span.Attributes[0].KeyValue)
+		require.Equal(t, attribute.Int64("http.status_code", 200), span.Attributes[1].KeyValue)
+		require.Equal(t, attribute.StringSlice("tags", []string{"a", "b"}), span.Attributes[2].KeyValue)
+	})
+
+	t.Run("resource and scope", func(t *testing.T) {
+		require.Equal(t, []attribute.KeyValue{attribute.String("service.name", "checkout")}, span.Resource.Attributes())
+		require.Equal(t, "net/http", span.InstrumentationScope.Name)
+		require.Equal(t, "1.2.3", span.InstrumentationScope.Version)
+	})
+
+	t.Run("events", func(t *testing.T) {
+		require.Len(t, span.Events, 1)
+		require.Equal(t, "exception", span.Events[0].Name)
+		require.Equal(t, []attribute.KeyValue{attribute.String("exception.type", "TimeoutError")}, span.Events[0].KeyValues())
+	})
+
+	t.Run("links", func(t *testing.T) {
+		require.Len(t, span.Links, 1)
+		require.Equal(t, span.SpanContext.TraceID(), span.Links[0].SpanContext.TraceID())
+		require.Equal(t, []attribute.KeyValue{attribute.String("link.kind", "producer")}, span.Links[0].KeyValues())
+	})
+}
+
+func TestToValue(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Value    *commonpb.AnyValue
+		Expected attribute.Value
+	}{
+		{
+			Name:     "bool",
+			Value:    &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}},
+			Expected: attribute.BoolValue(true),
+		},
+		{
+			Name:     "double",
+			Value:    &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1.5}},
+			Expected: attribute.Float64Value(1.5),
+		},
+		{
+			Name:     "bytes",
+			Value:    &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte("hi")}},
+			Expected: attribute.StringValue("aGk="),
+		},
+		{
+			Name: "mixed array",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+				Values: []*commonpb.AnyValue{
+					{Value: &commonpb.AnyValue_StringValue{StringValue: "a"}},
+					{Value: &commonpb.AnyValue_IntValue{IntValue: 1}},
+				},
+			}}},
+			Expected: attribute.StringValue(`["a",1]`),
+		},
+		{
+			Name: "kvlist",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+				Values: []*commonpb.KeyValue{stringValue("a", "b")},
+			}}},
+			Expected: attribute.StringValue(`{"a":"b"}`),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			value, ok := toValue(tc.Value)
+			require.True(t, ok)
+			require.Equal(t, tc.Expected, value)
+		})
+	}
+}
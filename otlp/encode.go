@@ -0,0 +1,216 @@
+package otlp
+
+import (
+	"romulus/domain"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// FromSpans is the reverse of ToSpans, grouping spans back into the resource and scope
+// hierarchy of an OTLP payload.  Groups are in the order their first span appears.
+func FromSpans(spans domain.Spans) []*tracepb.ResourceSpans {
+	type resourceGroup struct {
+		rs     *tracepb.ResourceSpans
+		scopes map[scopeKey]*tracepb.ScopeSpans
+	}
+
+	resources := map[resourceKey]*resourceGroup{}
+	results := []*tracepb.ResourceSpans{}
+
+	for _, span := range spans {
+		rkey := keyOfResource(span.Resource)
+		group, found := resources[rkey]
+		if !found {
+			group = &resourceGroup{
+				rs:     fromResource(span.Resource),
+				scopes: map[scopeKey]*tracepb.ScopeSpans{},
+			}
+			resources[rkey] = group
+			results = append(results, group.rs)
+		}
+
+		skey := keyOfScope(span.InstrumentationScope.Scope)
+		ss, found := group.scopes[skey]
+		if !found {
+			ss = &tracepb.ScopeSpans{
+				Scope:     fromScope(span.InstrumentationScope.Scope),
+				SchemaUrl: span.InstrumentationScope.SchemaURL,
+			}
+			group.scopes[skey] = ss
+			group.rs.ScopeSpans = append(group.rs.ScopeSpans, ss)
+		}
+
+		ss.Spans = append(ss.Spans, fromSpan(span))
+	}
+
+	return results
+}
+
+type resourceKey struct {
+	attributes attribute.Distinct
+	schemaURL  string
+}
+
+func keyOfResource(r *domain.Resource) resourceKey {
+	if r == nil || r.Resource == nil {
+		return resourceKey{}
+	}
+	return resourceKey{r.Equivalent(), r.SchemaURL()}
+}
+
+type scopeKey struct {
+	name       string
+	version    string
+	schemaURL  string
+	attributes attribute.Distinct
+}
+
+func keyOfScope(s instrumentation.Scope) scopeKey {
+	return scopeKey{s.Name, s.Version, s.SchemaURL, s.Attributes.Equivalent()}
+}
+
+func fromResource(r *domain.Resource) *tracepb.ResourceSpans {
+	if r == nil || r.Resource == nil {
+		return &tracepb.ResourceSpans{Resource: &resourcepb.Resource{}}
+	}
+
+	return &tracepb.ResourceSpans{
+		Resource:  &resourcepb.Resource{Attributes: fromAttributes(r.Attributes())},
+		SchemaUrl: r.SchemaURL(),
+	}
+}
+
+func fromScope(s instrumentation.Scope) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:       s.Name,
+		Version:    s.Version,
+		Attributes: fromAttributes(s.Attributes.ToSlice()),
+	}
+}
+
+func fromSpan(span *domain.Span) *tracepb.Span {
+	sc := span.SpanContext
+
+	ps := &tracepb.Span{
+		TraceId:                fromTraceID(sc.TraceID()),
+		SpanId:                 fromSpanID(sc.SpanID()),
+		TraceState:             sc.TraceState().String(),
+		Name:                   span.Name,
+		Kind:                   tracepb.Span_SpanKind(span.SpanKind),
+		StartTimeUnixNano:      fromTime(span.StartTime),
+		EndTimeUnixNano:        fromTime(span.EndTime),
+		DroppedAttributesCount: uint32(span.DroppedAttributes),
+		DroppedEventsCount:     uint32(span.DroppedEvents),
+		DroppedLinksCount:      uint32(span.DroppedLinks),
+		Status:                 fromStatus(span.Status),
+	}
+
+	if span.Parent.SpanID().IsValid() {
+		ps.ParentSpanId = fromSpanID(span.Parent.SpanID())
+	}
+
+	attrs := make([]attribute.KeyValue, len(span.Attributes))
+	for i, attr := range span.Attributes {
+		attrs[i] = attr.KeyValue
+	}
+	ps.Attributes = fromAttributes(attrs)
+
+	for _, e := range span.Events {
+		ps.Events = append(ps.Events, &tracepb.Span_Event{
+			Name:                   e.Name,
+			TimeUnixNano:           fromTime(e.Time),
+			Attributes:             fromAttributes(e.KeyValues()),
+			DroppedAttributesCount: uint32(e.DroppedAttributeCount),
+		})
+	}
+
+	for _, l := range span.Links {
+		ps.Links = append(ps.Links, &tracepb.Span_Link{
+			TraceId:                fromTraceID(l.SpanContext.TraceID()),
+			SpanId:                 fromSpanID(l.SpanContext.SpanID()),
+			TraceState:             l.SpanContext.TraceState().String(),
+			Attributes:             fromAttributes(l.KeyValues()),
+			DroppedAttributesCount: uint32(l.DroppedAttributeCount),
+		})
+	}
+
+	return ps
+}
+
+func fromStatus(s sdktrace.Status) *tracepb.Status {
+	status := &tracepb.Status{Message: s.Description}
+
+	switch s.Code {
+	case codes.Ok:
+		status.Code = tracepb.Status_STATUS_CODE_OK
+	case codes.Error:
+		status.Code = tracepb.Status_STATUS_CODE_ERROR
+	default:
+		status.Code = tracepb.Status_STATUS_CODE_UNSET
+	}
+
+	return status
+}
+
+func fromTraceID(tid trace.TraceID) []byte {
+	return tid[:]
+}
+
+func fromSpanID(sid trace.SpanID) []byte {
+	return sid[:]
+}
+
+func fromTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func fromAttributes(attrs []attribute.KeyValue) []*commonpb.KeyValue {
+	kvs := make([]*commonpb.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		kvs = append(kvs, &commonpb.KeyValue{Key: string(attr.Key), Value: fromValue(attr.Value)})
+	}
+	return kvs
+}
+
+// fromValue converts an attribute value into an OTLP AnyValue, with slices becoming arrays
+func fromValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.STRING:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case attribute.BOOLSLICE:
+		return fromSlice(v.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return fromSlice(v.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return fromSlice(v.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return fromSlice(v.AsStringSlice(), attribute.StringValue)
+	}
+
+	return &commonpb.AnyValue{}
+}
+
+func fromSlice[T any](sl []T, value func(T) attribute.Value) *commonpb.AnyValue {
+	values := make([]*commonpb.AnyValue, len(sl))
+	for i, v := range sl {
+		values[i] = fromValue(value(v))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+}
@@ -0,0 +1,124 @@
+package otlp
+
+import (
+	"romulus/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func encodeSpan(name string, res *domain.Resource, scope instrumentation.Scope, sid byte, parent byte) *domain.Span {
+	start := time.Date(2025, 6, 1, 15, 0, 0, 0, time.UTC)
+	tid := trace.TraceID{1, 2, 3}
+
+	span := &domain.Span{
+		Name:        name,
+		SpanContext: domain.SpanContext{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{sid}, TraceFlags: trace.FlagsSampled})},
+		SpanKind:    trace.SpanKindServer,
+		StartTime:   start,
+		EndTime:     start.Add(time.Second),
+		Attributes: []domain.Attribute{
+			{KeyValue: attribute.String("http.route", "/")},
+			{KeyValue: attribute.Int64Slice("codes", []int64{1, 2})},
+		},
+		Events: domain.NewEvents([]sdktrace.Event{{
+			Name:       "exception",
+			Attributes: []attribute.KeyValue{attribute.String("exception.type", "TimeoutError")},
+			Time:       start.Add(time.Millisecond),
+		}}),
+		Links: domain.NewLinks([]sdktrace.Link{{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{9}, SpanID: trace.SpanID{9}, TraceFlags: trace.FlagsSampled}),
+			Attributes:  []attribute.KeyValue{attribute.Bool("follows", true)},
+		}}),
+		Status:               sdktrace.Status{Code: codes.Error, Description: "broken"},
+		DroppedAttributes:    1,
+		Resource:             res,
+		InstrumentationScope: domain.Scope{Scope: scope},
+	}
+
+	if parent != 0 {
+		span.Parent = domain.SpanContext{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: trace.SpanID{parent}, TraceFlags: trace.FlagsSampled})}
+	}
+
+	return span
+}
+
+func TestFromSpans(t *testing.T) {
+	api := &domain.Resource{Resource: resource.NewWithAttributes("", attribute.String("service.name", "api"))}
+	db := &domain.Resource{Resource: resource.NewWithAttributes("", attribute.String("service.name", "db"))}
+	http := instrumentation.Scope{Name: "net/http", Version: "1.2.3", Attributes: attribute.NewSet()}
+	sql := instrumentation.Scope{Name: "database/sql", Attributes: attribute.NewSet()}
+
+	spans := domain.Spans{
+		encodeSpan("GET /", api, http, 1, 0),
+		encodeSpan("query", db, sql, 2, 1),
+		encodeSpan("render", api, sql, 3, 1),
+		encodeSpan("GET /static", api, http, 4, 1),
+	}
+
+	rs := FromSpans(spans)
+
+	t.Run("grouping", func(t *testing.T) {
+		require.Len(t, rs, 2)
+		require.Len(t, rs[0].ScopeSpans, 2)
+		require.Equal(t, "net/http", rs[0].ScopeSpans[0].Scope.Name)
+		require.Len(t, rs[0].ScopeSpans[0].Spans, 2)
+		require.Equal(t, "database/sql", rs[0].ScopeSpans[1].Scope.Name)
+		require.Len(t, rs[1].ScopeSpans, 1)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		converted, rejected, err := ToSpans(rs)
+		require.NoError(t, err)
+		require.Zero(t, rejected)
+		require.Len(t, converted, len(spans))
+
+		byName := map[string]domain.Span{}
+		for _, span := range converted {
+			byName[span.Name] = span
+		}
+
+		for _, expected := range spans {
+			actual := byName[expected.Name]
+			require.Equal(t, expected.SpanContext.SpanContext, actual.SpanContext.SpanContext)
+			require.Equal(t, expected.Parent.SpanContext, actual.Parent.SpanContext)
+			require.Equal(t, expected.SpanKind, actual.SpanKind)
+			require.True(t, expected.StartTime.Equal(actual.StartTime))
+			require.True(t, expected.EndTime.Equal(actual.EndTime))
+			require.Equal(t, expected.Attributes, actual.Attributes)
+			require.Equal(t, expected.Events[0].KeyValues(), actual.Events[0].KeyValues())
+			require.Equal(t, expected.Links[0].SpanContext.SpanContext, actual.Links[0].SpanContext.SpanContext)
+			require.Equal(t, expected.Links[0].KeyValues(), actual.Links[0].KeyValues())
+			require.Equal(t, expected.Status, actual.Status)
+			require.Equal(t, expected.DroppedAttributes, actual.DroppedAttributes)
+			require.Equal(t, expected.Resource.Attributes(), actual.Resource.Attributes())
+			require.Equal(t, expected.InstrumentationScope.Name, actual.InstrumentationScope.Name)
+			require.Equal(t, expected.InstrumentationScope.Version, actual.InstrumentationScope.Version)
+		}
+	})
+}
+
+func TestMarshalJSON(t *testing.T) {
+	res := &domain.Resource{Resource: resource.NewWithAttributes("", attribute.String("service.name", "api"))}
+	req := &collectortrace.ExportTraceServiceRequest{
+		ResourceSpans: FromSpans(domain.Spans{encodeSpan("GET /", res, instrumentation.Scope{Name: "tests"}, 1, 2)}),
+	}
+
+	b, err := MarshalJSON(req)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"spanId":"0100000000000000"`)
+	require.Contains(t, string(b), `"kind":2`)
+
+	read := &collectortrace.ExportTraceServiceRequest{}
+	require.NoError(t, UnmarshalJSON(b, read))
+	require.True(t, proto.Equal(req, read))
+}
@@ -0,0 +1,115 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// OTLP/JSON differs from the standard protobuf json mapping by encoding trace and span ids
+// as hex rather than base64, so these fields are rewritten before handing off to protojson.
+var idFields = map[string]bool{
+	"traceId":        true,
+	"trace_id":       true,
+	"spanId":         true,
+	"span_id":        true,
+	"parentSpanId":   true,
+	"parent_span_id": true,
+}
+
+// OTLP/JSON also requires enums to be encoded as numbers
+var marshalOptions = protojson.MarshalOptions{UseEnumNumbers: true}
+
+// UnmarshalJSON decodes an OTLP/JSON encoded export request
+func UnmarshalJSON(b []byte, req *collectortrace.ExportTraceServiceRequest) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return err
+	}
+
+	if err := rewriteIds(doc, hexToBase64); err != nil {
+		return err
+	}
+
+	rewritten, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(rewritten, req)
+}
+
+// MarshalJSON encodes an export request as OTLP/JSON
+func MarshalJSON(req *collectortrace.ExportTraceServiceRequest) ([]byte, error) {
+	b, err := marshalOptions.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if err := rewriteIds(doc, base64ToHex); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(doc)
+}
+
+func hexToBase64(s string) (string, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func base64ToHex(s string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func rewriteIds(node any, rewrite func(string) (string, error)) error {
+	switch val := node.(type) {
+	case map[string]any:
+		for key, child := range val {
+			if s, ok := child.(string); ok && idFields[key] {
+				id, err := rewrite(s)
+				if err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				val[key] = id
+				continue
+			}
+
+			if err := rewriteIds(child, rewrite); err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		for _, child := range val {
+			if err := rewriteIds(child, rewrite); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
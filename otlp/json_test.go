@@ -0,0 +1,62 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	body := `{
+  "resourceSpans": [{
+    "resource": {
+      "attributes": [{ "key": "service.name", "value": { "stringValue": "checkout" } }]
+    },
+    "scopeSpans": [{
+      "scope": { "name": "tests" },
+      "spans": [{
+        "traceId": "5b8efff798038103d269b633813fc60c",
+        "spanId": "eee19b7ec3c1b174",
+        "parentSpanId": "eee19b7ec3c1b173",
+        "name": "GET /",
+        "kind": 2,
+        "startTimeUnixNano": "1544712660000000000",
+        "endTimeUnixNano": "1544712661000000000",
+        "attributes": [
+          { "key": "traceId", "value": { "stringValue": "not an id" } },
+          { "key": "http.status_code", "value": { "intValue": "200" } }
+        ],
+        "links": [{
+          "traceId": "5b8efff798038103d269b633813fc60d",
+          "spanId": "eee19b7ec3c1b175"
+        }],
+        "status": { "code": "STATUS_CODE_ERROR" }
+      }]
+    }]
+  }]
+}`
+
+	req := &collectortrace.ExportTraceServiceRequest{}
+	require.NoError(t, UnmarshalJSON([]byte(body), req))
+
+	spans, rejected, err := ToSpans(req.ResourceSpans)
+	require.NoError(t, err)
+	require.Zero(t, rejected)
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", span.SpanContext.TraceID().String())
+	require.Equal(t, "eee19b7ec3c1b174", span.SpanContext.SpanID().String())
+	require.Equal(t, "eee19b7ec3c1b173", span.Parent.SpanID().String())
+	require.Equal(t, "5b8efff798038103d269b633813fc60d", span.Links[0].SpanContext.TraceID().String())
+	require.Equal(t, "not an id", span.Attributes[0].Value.AsString())
+	require.Equal(t, int64(200), span.Attributes[1].Value.AsInt64())
+}
+
+func TestUnmarshalJSONInvalidId(t *testing.T) {
+	body := `{ "resourceSpans": [{ "scopeSpans": [{ "spans": [{ "traceId": "not hex" }] }] }] }`
+
+	req := &collectortrace.ExportTraceServiceRequest{}
+	require.Error(t, UnmarshalJSON([]byte(body), req))
+}
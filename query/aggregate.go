@@ -0,0 +1,445 @@
+package query
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"fmt"
+	"maps"
+	"romulus/domain"
+	"romulus/sketch"
+	"romulus/storage"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// durationField is the default field for percentiles
+const durationField = "duration_ms"
+
+// Aggregate is the result of a single aggregation.  Value is nil when there was nothing to
+// aggregate, such as the average of a field which no span has.
+type Aggregate struct {
+	Name  string   `json:"name"`
+	Value *float64 `json:"value"`
+}
+
+// Series is the aggregates for one combination of group by values, keyed by field.  Fields a
+// span doesn't have are left out of the key.  Other is set for the group which combines every
+// group past the limit.
+type Series struct {
+	Key    map[string]string `json:"key"`
+	Other  bool              `json:"other,omitempty"`
+	Points []Point           `json:"points"`
+}
+
+// Point is the aggregates for the bucket starting at Time, or for the whole time range when
+// the query has no bucket width
+type Point struct {
+	Time       time.Time   `json:"time"`
+	Aggregates []Aggregate `json:"aggregates"`
+}
+
+type aggregator interface {
+	add(span *domain.Span)
+	// merge combines another aggregator of the same kind into this one
+	merge(other aggregator)
+	value() *float64
+}
+
+type aggregation struct {
+	requiresField bool
+	defaultField  string
+	create        func(field string) aggregator
+}
+
+var aggregations = map[string]aggregation{
+	"count":          {create: newCount},
+	"count_distinct": {create: newCountDistinct},
+	"distinct_count": {create: newCountDistinct},
+	"sum":            {requiresField: true, create: newStats(func(s *sketch.DDSketch) float64 { return s.Sum() })},
+	"avg":            {requiresField: true, create: newStats(func(s *sketch.DDSketch) float64 { return s.Sum() / float64(s.Count()) })},
+	"min":            {requiresField: true, create: newStats(func(s *sketch.DDSketch) float64 { return s.Min() })},
+	"max":            {requiresField: true, create: newStats(func(s *sketch.DDSketch) float64 { return s.Max() })},
+	"p50":            {defaultField: durationField, create: newPercentile(0.5)},
+	"p90":            {defaultField: durationField, create: newPercentile(0.9)},
+	"p99":            {defaultField: durationField, create: newPercentile(0.99)},
+}
+
+// resolveCall checks the call is a known aggregation, and fills in its default field
+func resolveCall(call Call) (Call, error) {
+	agg, found := aggregations[call.Name]
+	if !found {
+		return Call{}, fmt.Errorf("unknown aggregation %s", call)
+	}
+
+	if call.Field == "" {
+		if agg.requiresField {
+			return Call{}, fmt.Errorf("%s needs a field", call)
+		}
+		call.Field = agg.defaultField
+	}
+
+	return call, nil
+}
+
+// group accumulates the aggregators for each bucket of a group.  Estimate is the number of
+// spans used to rank the group, which includes the spans of the groups it replaced.
+type group struct {
+	id       string
+	key      map[string]string
+	spans    int
+	estimate int
+	index    int
+	buckets  map[int64][]aggregator
+}
+
+// minLiveGroups is the fewest groups tracked while streaming, before the quietest are folded
+// into other.  At least ten times the limit are tracked, so the busiest groups are accurate.
+const minLiveGroups = 1000
+
+// maxLiveCells bounds the live groups times the buckets in each, as every cell holds its own
+// aggregators.  With many buckets fewer groups are tracked, but never fewer than the limit.
+const maxLiveCells = 100_000
+
+// liveGroups is the capacity of the live groups for the plan
+func liveGroups(plan *Plan) int {
+	capacity := max(minLiveGroups, plan.Limit*10)
+	return max(plan.Limit, min(capacity, maxLiveCells/plan.buckets()))
+}
+
+// aggregate streams the matching spans through every aggregation at once, splitting them by
+// group and bucket.  Without a group by there is always exactly one group.
+func aggregate(ctx context.Context, reader *storage.Reader, plan *Plan) ([]Series, error) {
+	groups := newGroups(plan, liveGroups(plan))
+	if len(plan.GroupBy) == 0 {
+		groups.find("", map[string]string{})
+	}
+
+	for at, err := range reader.Spans(ctx, plan.Range, plan.SpanWhere) {
+		if err != nil {
+			return nil, err
+		}
+
+		groups.add(at)
+	}
+
+	return groups.series(), nil
+}
+
+// groups holds the live groups while streaming, never more than capacity of them.  When a span
+// starts a new group and there's no room, the group with the lowest estimate is folded into
+// other, and the new group takes its place starting from that estimate.  This is the space
+// saving algorithm: a group with more than 1/capacity of the spans is never folded away, so
+// the busiest groups are kept, at the cost of a returning group only aggregating its later
+// spans.
+type groups struct {
+	plan     *Plan
+	capacity int
+	live     map[string]*group
+	ranked   groupHeap
+	other    *group
+}
+
+func newGroups(plan *Plan, capacity int) *groups {
+	return &groups{
+		plan:     plan,
+		capacity: capacity,
+		live:     map[string]*group{},
+	}
+}
+
+func (gs *groups) add(at storage.SpanAt) {
+	g := gs.find(groupKey(at.Span, gs.plan.GroupBy))
+	g.spans++
+	g.estimate++
+	heap.Fix(&gs.ranked, g.index)
+
+	bucket := gs.plan.bucketOf(at.Epoch)
+	aggregators, found := g.buckets[bucket]
+	if !found {
+		aggregators = gs.plan.newAggregators()
+		g.buckets[bucket] = aggregators
+	}
+
+	for _, agg := range aggregators {
+		agg.add(at.Span)
+	}
+}
+
+// find returns the live group for the id, starting a new group when there isn't one
+func (gs *groups) find(id string, key map[string]string) *group {
+	if g, found := gs.live[id]; found {
+		return g
+	}
+
+	g := &group{id: id, key: key, buckets: map[int64][]aggregator{}}
+	gs.live[id] = g
+
+	if len(gs.ranked) < gs.capacity {
+		heap.Push(&gs.ranked, g)
+		return g
+	}
+
+	quietest := gs.ranked[0]
+	delete(gs.live, quietest.id)
+	gs.fold(quietest)
+
+	g.estimate = quietest.estimate
+	g.index = 0
+	gs.ranked[0] = g
+
+	return g
+}
+
+func (gs *groups) fold(g *group) {
+	if gs.other == nil {
+		gs.other = &group{key: map[string]string{}, buckets: map[int64][]aggregator{}}
+	}
+	gs.other.mergeFrom(g, gs.plan)
+}
+
+// series returns the busiest groups up to the limit, and the rest combined into a single other
+// group
+func (gs *groups) series() []Series {
+	ranked := slices.SortedFunc(maps.Values(gs.live), func(a, b *group) int {
+		return cmp.Or(cmp.Compare(b.estimate, a.estimate), strings.Compare(a.id, b.id))
+	})
+
+	if len(ranked) > gs.plan.Limit {
+		for _, g := range ranked[gs.plan.Limit:] {
+			gs.fold(g)
+		}
+		ranked = ranked[:gs.plan.Limit]
+	}
+
+	results := make([]Series, 0, len(ranked)+1)
+	for _, g := range ranked {
+		results = append(results, Series{Key: g.key, Points: gs.plan.points(g)})
+	}
+	if gs.other != nil {
+		results = append(results, Series{Key: gs.other.key, Other: true, Points: gs.plan.points(gs.other)})
+	}
+
+	return results
+}
+
+// groupHeap orders groups by their estimate, lowest first
+type groupHeap []*group
+
+func (h groupHeap) Len() int           { return len(h) }
+func (h groupHeap) Less(i, j int) bool { return h[i].estimate < h[j].estimate }
+
+func (h groupHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *groupHeap) Push(x any) {
+	g := x.(*group)
+	g.index = len(*h)
+	*h = append(*h, g)
+}
+
+func (h *groupHeap) Pop() any {
+	old := *h
+	g := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return g
+}
+
+// groupKey finds the span's values for the group by fields, and an id which is unique to the
+// combination of values and their types
+func groupKey(span *domain.Span, fields []string) (string, map[string]string) {
+	key := make(map[string]string, len(fields))
+	id := strings.Builder{}
+
+	for _, field := range fields {
+		if value, found := span.Value(field); found {
+			key[field] = value.Emit()
+			id.WriteString(value.Type().String() + ":" + value.Emit())
+		}
+		id.WriteByte(0)
+	}
+
+	return id.String(), key
+}
+
+func (g *group) mergeFrom(other *group, plan *Plan) {
+	g.spans += other.spans
+
+	for bucket, aggregators := range other.buckets {
+		into, found := g.buckets[bucket]
+		if !found {
+			into = plan.newAggregators()
+			g.buckets[bucket] = into
+		}
+
+		for i, agg := range aggregators {
+			into[i].merge(agg)
+		}
+	}
+}
+
+func (p *Plan) newAggregators() []aggregator {
+	aggregators := make([]aggregator, len(p.Aggregations))
+	for i, call := range p.Aggregations {
+		aggregators[i] = aggregations[call.Name].create(call.Field)
+	}
+	return aggregators
+}
+
+// bucketOf is the epoch second the bucket containing the epoch starts at
+func (p *Plan) bucketOf(epoch int64) int64 {
+	if p.Bucket == 0 {
+		return p.Range.Start.Unix()
+	}
+
+	width := int64(p.Bucket / time.Second)
+	return epoch - epoch%width
+}
+
+// buckets is the number of points in each series
+func (p *Plan) buckets() int {
+	if p.Bucket == 0 {
+		return 1
+	}
+
+	width := int64(p.Bucket / time.Second)
+	return int((p.bucketOf(p.Range.Finish.Unix())-p.bucketOf(p.Range.Start.Unix()))/width) + 1
+}
+
+// points turns a group's buckets into a series, including empty buckets so the series has no
+// gaps
+func (p *Plan) points(g *group) []Point {
+	first := p.bucketOf(p.Range.Start.Unix())
+	last := p.bucketOf(p.Range.Finish.Unix())
+
+	width := int64(1)
+	if p.Bucket > 0 {
+		width = int64(p.Bucket / time.Second)
+	}
+
+	points := []Point{}
+	for bucket := first; bucket <= last; bucket += width {
+		aggregators, found := g.buckets[bucket]
+		if !found {
+			aggregators = p.newAggregators()
+		}
+
+		aggregates := make([]Aggregate, len(aggregators))
+		for i, agg := range aggregators {
+			aggregates[i] = Aggregate{Name: p.Aggregations[i].String(), Value: agg.value()}
+		}
+
+		points = append(points, Point{Time: time.Unix(bucket, 0).UTC(), Aggregates: aggregates})
+	}
+
+	return points
+}
+
+// count counts spans, or only spans with the field when there is one
+type count struct {
+	field string
+	n     float64
+}
+
+func newCount(field string) aggregator {
+	return &count{field: field}
+}
+
+func (c *count) add(span *domain.Span) {
+	if c.field != "" {
+		if _, found := span.Value(c.field); !found {
+			return
+		}
+	}
+	c.n++
+}
+
+func (c *count) merge(other aggregator) {
+	c.n += other.(*count).n
+}
+
+func (c *count) value() *float64 {
+	return &c.n
+}
+
+// countDistinct estimates the number of distinct values of the field, or the number of distinct
+// traces when there is no field
+type countDistinct struct {
+	field  string
+	sketch *sketch.HyperLogLog
+}
+
+func newCountDistinct(field string) aggregator {
+	return &countDistinct{field: field, sketch: sketch.NewHyperLogLog()}
+}
+
+func (c *countDistinct) add(span *domain.Span) {
+	if c.field == "" {
+		c.sketch.Add(span.SpanContext.TraceID().String())
+		return
+	}
+
+	if value, found := span.Value(c.field); found {
+		// the type is included so the int 1 and the string "1" are counted separately
+		c.sketch.Add(value.Type().String() + ":" + value.Emit())
+	}
+}
+
+func (c *countDistinct) merge(other aggregator) {
+	c.sketch.Merge(other.(*countDistinct).sketch)
+}
+
+func (c *countDistinct) value() *float64 {
+	n := float64(c.sketch.Count())
+	return &n
+}
+
+// stats collects a numeric field into a sketch, and reports one of its statistics
+type stats struct {
+	field  string
+	sketch *sketch.DDSketch
+	report func(*sketch.DDSketch) float64
+}
+
+func newStats(report func(*sketch.DDSketch) float64) func(string) aggregator {
+	return func(field string) aggregator {
+		return &stats{field: field, sketch: sketch.NewDDSketch(), report: report}
+	}
+}
+
+func newPercentile(q float64) func(string) aggregator {
+	return newStats(func(s *sketch.DDSketch) float64 { return s.Quantile(q) })
+}
+
+func (s *stats) add(span *domain.Span) {
+	value, found := span.Value(s.field)
+	if !found {
+		return
+	}
+
+	switch value.Type() {
+	case attribute.INT64:
+		s.sketch.Add(float64(value.AsInt64()))
+	case attribute.FLOAT64:
+		s.sketch.Add(value.AsFloat64())
+	}
+}
+
+func (s *stats) merge(other aggregator) {
+	s.sketch.Merge(other.(*stats).sketch)
+}
+
+func (s *stats) value() *float64 {
+	if s.sketch.Count() == 0 {
+		return nil
+	}
+
+	v := s.report(s.sketch)
+	return &v
+}
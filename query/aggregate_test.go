@@ -0,0 +1,158 @@
+package query
+
+import (
+	"fmt"
+	"romulus/domain"
+	"romulus/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	run, _ := executor(t)
+
+	t.Run("aggregations", func(t *testing.T) {
+		result := run(t, `select count(), count(retry), distinct_count(), count_distinct(name), sum(http.status_code), avg(duration_ms), min(duration_ms), max(duration_ms), p50(), p99(missing) since 15m`)
+
+		values := map[string]any{}
+		for _, agg := range result.Aggregates {
+			if agg.Name == "p50(duration_ms)" {
+				// percentiles are estimates, within 1% of the real value
+				require.InEpsilon(t, 1000, *agg.Value, 0.01)
+				continue
+			}
+
+			if agg.Value == nil {
+				values[agg.Name] = nil
+			} else {
+				values[agg.Name] = *agg.Value
+			}
+		}
+
+		require.Equal(t, map[string]any{
+			"count()":               2.0,
+			"count(retry)":          1.0,
+			"distinct_count()":      1.0,
+			"count_distinct(name)":  2.0,
+			"sum(http.status_code)": 500.0,
+			"avg(duration_ms)":      2000.0,
+			"min(duration_ms)":      1000.0,
+			"max(duration_ms)":      3000.0,
+			"p99(missing)":          nil,
+		}, values)
+	})
+
+	t.Run("aggregations with a filter", func(t *testing.T) {
+		result := run(t, `count() where retry = true since 15m`)
+		require.Equal(t, 1.0, *result.Aggregates[0].Value)
+	})
+
+	t.Run("group by", func(t *testing.T) {
+		result := run(t, `count(), max(duration_ms) group by name, retry since 15m`)
+		require.Len(t, result.Series, 2)
+
+		for _, series := range result.Series {
+			require.Len(t, series.Points, 1)
+			require.Equal(t, 1.0, *series.Points[0].Aggregates[0].Value)
+		}
+
+		require.Equal(t, map[string]string{"name": "child", "retry": "true"}, result.Series[0].Key)
+		require.Equal(t, 1000.0, *result.Series[0].Points[0].Aggregates[1].Value)
+		require.Equal(t, map[string]string{"name": "root"}, result.Series[1].Key)
+		require.Equal(t, 3000.0, *result.Series[1].Points[0].Aggregates[1].Value)
+	})
+
+	t.Run("limit combines the rest into other", func(t *testing.T) {
+		result := run(t, `count() group by name limit 1 since 15m`)
+		require.Len(t, result.Series, 2)
+		require.False(t, result.Series[0].Other)
+		require.True(t, result.Series[1].Other)
+		require.Equal(t, 1.0, *result.Series[1].Points[0].Aggregates[0].Value)
+	})
+
+	t.Run("time buckets", func(t *testing.T) {
+		result := run(t, `count() every 1m since 15m`)
+		require.Len(t, result.Series, 1)
+
+		series := result.Series[0]
+		require.Len(t, series.Points, 16)
+
+		total := 0.0
+		for i, point := range series.Points {
+			if i > 0 {
+				require.Equal(t, time.Minute, point.Time.Sub(series.Points[i-1].Time))
+			}
+			total += *point.Aggregates[0].Value
+		}
+		require.Equal(t, 2.0, total)
+	})
+}
+
+func TestGroupsCapacity(t *testing.T) {
+	now := time.Now()
+	plan := &Plan{
+		Range:        storage.Range{Start: now.Add(-time.Hour), Finish: now},
+		Aggregations: []Call{{Name: "count"}},
+		GroupBy:      []string{"name"},
+		Limit:        2,
+	}
+
+	groups := newGroups(plan, 5)
+	add := func(name string) {
+		groups.add(storage.SpanAt{Epoch: now.Unix(), Span: &domain.Span{Name: name}})
+		require.LessOrEqual(t, len(groups.live), 5)
+		require.Len(t, groups.ranked, len(groups.live))
+	}
+
+	// the two busy groups each have more than a fifth of the spans, so are never folded away
+	// by the quiet groups, which only appear once each
+	for i := range 100 {
+		add(fmt.Sprintf("quiet-%d", i))
+		if i%2 == 0 {
+			add("a")
+		}
+		if i%5 == 0 {
+			add("b")
+			add("b")
+		}
+	}
+
+	series := groups.series()
+	require.Len(t, series, 3)
+	require.Equal(t, map[string]string{"name": "a"}, series[0].Key)
+	require.Equal(t, map[string]string{"name": "b"}, series[1].Key)
+	require.True(t, series[2].Other)
+
+	// every span is counted exactly once, whichever group it ended up in
+	total := 0.0
+	for _, s := range series {
+		require.Len(t, s.Points, 1)
+		total += *s.Points[0].Aggregates[0].Value
+	}
+	require.Equal(t, 190.0, total)
+}
+
+func TestLiveGroups(t *testing.T) {
+	now := time.Unix(1_750_000_000, 0)
+	day := storage.Range{Start: now.Add(-24 * time.Hour), Finish: now}
+
+	cases := []struct {
+		Name     string
+		Plan     *Plan
+		Expected int
+	}{
+		{"single point", &Plan{Range: day, Limit: 10}, minLiveGroups},
+		{"large limit", &Plan{Range: day, Limit: 500}, 5000},
+		{"few buckets", &Plan{Range: day, Bucket: time.Hour, Limit: 10}, minLiveGroups},
+		{"many buckets", &Plan{Range: day, Bucket: 10 * time.Second, Limit: 10}, maxLiveCells / 8641},
+		{"never below the limit", &Plan{Range: day, Bucket: 10 * time.Second, Limit: 50}, 50},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.Equal(t, tc.Expected, liveGroups(tc.Plan))
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Query is the parsed form of
+//
+//	[select <target> | <aggregations>] [where <expr>]
+//	[group by <field>, ...] [every <duration>] [limit <n>] [since <time>] [until <time>]
+type Query struct {
+	Aggregations []Call
+	Where        Expr
+	GroupBy      []string
+	Every        string
+	Limit        int
+	Since        *TimeRef
+	Until        *TimeRef
+}
+
+func (q *Query) String() string {
+	parts := []string{}
+
+	if len(q.Aggregations) == 0 {
+		parts = append(parts, "select traces")
+	} else {
+		calls := make([]string, len(q.Aggregations))
+		for i, call := range q.Aggregations {
+			calls[i] = call.String()
+		}
+		parts = append(parts, "select "+strings.Join(calls, ", "))
+	}
+
+	if q.Where != nil {
+		parts = append(parts, "where "+q.Where.String())
+	}
+	if len(q.GroupBy) > 0 {
+		parts = append(parts, "group by "+strings.Join(q.GroupBy, ", "))
+	}
+	if q.Every != "" {
+		parts = append(parts, "every "+q.Every)
+	}
+	if q.Limit > 0 {
+		parts = append(parts, "limit "+strconv.Itoa(q.Limit))
+	}
+	if q.Since != nil {
+		parts = append(parts, "since "+q.Since.String())
+	}
+	if q.Until != nil {
+		parts = append(parts, "until "+q.Until.String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Call is an aggregation function, such as `count()` or `avg(duration_ms)`
+type Call struct {
+	Name  string
+	Field string
+}
+
+func (c Call) String() string {
+	return c.Name + "(" + c.Field + ")"
+}
+
+type Expr interface {
+	String() string
+	expr()
+}
+
+// BinaryExpr combines two expressions with And or Or
+type BinaryExpr struct {
+	Op    TokenKind
+	Left  Expr
+	Right Expr
+}
+
+// NotExpr negates an expression
+type NotExpr struct {
+	Expr Expr
+}
+
+// SpanExpr is written with braces, and requires everything inside to match on the same span.
+// Conditions outside of braces can each be matched by a different span in the trace.
+type SpanExpr struct {
+	Expr Expr
+}
+
+// Comparison is a single condition, such as `http.status_code >= 500`, or `exists(field)` when
+// the Op is Exists
+type Comparison struct {
+	Field string
+	Op    TokenKind
+	Value attribute.Value
+	// Quantifier is Any or All for `contains any (...)`, where the Value is a slice
+	Quantifier TokenKind
+	// Length compares the number of elements in the field: `len(field) > 2`
+	Length bool
+}
+
+func (*BinaryExpr) expr() {}
+func (*NotExpr) expr()    {}
+func (*SpanExpr) expr()   {}
+func (*Comparison) expr() {}
+
+func (e *BinaryExpr) String() string {
+	return "(" + e.Left.String() + " " + e.Op.String() + " " + e.Right.String() + ")"
+}
+
+func (e *NotExpr) String() string {
+	return "not " + e.Expr.String()
+}
+
+func (e *SpanExpr) String() string {
+	return "{ " + e.Expr.String() + " }"
+}
+
+func (e *Comparison) String() string {
+	if e.Op == Exists {
+		return "exists(" + e.Field + ")"
+	}
+
+	field := e.Field
+	if e.Length {
+		field = "len(" + e.Field + ")"
+	}
+
+	if e.Quantifier != EOF {
+		return field + " " + e.Op.String() + " " + e.Quantifier.String() + " " + formatList(e.Value)
+	}
+	return field + " " + e.Op.String() + " " + formatValue(e.Value)
+}
+
+func formatValue(v attribute.Value) string {
+	if v.Type() == attribute.STRING {
+		return strconv.Quote(v.AsString())
+	}
+	return v.Emit()
+}
+
+func formatList(v attribute.Value) string {
+	values := []string{}
+
+	switch v.Type() {
+	case attribute.BOOLSLICE:
+		for _, b := range v.AsBoolSlice() {
+			values = append(values, formatValue(attribute.BoolValue(b)))
+		}
+	case attribute.INT64SLICE:
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, formatValue(attribute.Int64Value(i)))
+		}
+	case attribute.FLOAT64SLICE:
+		for _, f := range v.AsFloat64Slice() {
+			values = append(values, formatValue(attribute.Float64Value(f)))
+		}
+	case attribute.STRINGSLICE:
+		for _, s := range v.AsStringSlice() {
+			values = append(values, formatValue(attribute.StringValue(s)))
+		}
+	}
+
+	return "(" + strings.Join(values, ", ") + ")"
+}
+
+// TimeRef is the argument to since or until, which is resolved relative to the current time
+// when the query is compiled.
+type TimeRef struct {
+	Kind TokenKind
+	Text string
+}
+
+func (t *TimeRef) String() string {
+	if t.Kind == String {
+		return strconv.Quote(t.Text)
+	}
+	return t.Text
+}
+
+type ParseError struct {
+	Pos     int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Message)
+}
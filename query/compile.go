@@ -0,0 +1,376 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"romulus/storage"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultWindow = time.Hour
+
+// defaultGroupLimit is how many groups are returned before the rest are combined into "other"
+const defaultGroupLimit = 10
+
+// maxBuckets stops a small bucket width over a long range producing a huge series
+const maxBuckets = 10_000
+
+// traceIdField selects a single trace directly, rather than searching
+const traceIdField = "traceid"
+
+// Plan is a query compiled into the operations to run against the storage.Reader
+type Plan struct {
+	Range        storage.Range
+	TraceID      string
+	Where        storage.TraceExpr
+	Aggregations []Call
+	// SpanWhere is used instead of Where by aggregations, which are calculated over the matching
+	// spans rather than traces
+	SpanWhere storage.SpanExpr
+	GroupBy   []string
+	// Bucket is the width of each point in the series, or zero for a single point per group
+	Bucket time.Duration
+	Limit  int
+}
+
+// Compile resolves the query's times relative to now, and converts the where clause into a
+// trace expression.
+func Compile(q *Query, now time.Time) (*Plan, error) {
+	plan := &Plan{
+		Range:        storage.Range{Start: now.Add(-defaultWindow), Finish: now},
+		Aggregations: slices.Clone(q.Aggregations),
+	}
+
+	if q.Until != nil {
+		t, err := resolveTime(q.Until, now)
+		if err != nil {
+			return nil, fmt.Errorf("until: %w", err)
+		}
+		plan.Range.Finish = t
+		plan.Range.Start = t.Add(-defaultWindow)
+	}
+
+	if q.Since != nil {
+		t, err := resolveTime(q.Since, now)
+		if err != nil {
+			return nil, fmt.Errorf("since: %w", err)
+		}
+		plan.Range.Start = t
+	}
+
+	if plan.Range.Start.After(plan.Range.Finish) {
+		return nil, fmt.Errorf("since %s is after until %s", plan.Range.Start.Format(time.RFC3339), plan.Range.Finish.Format(time.RFC3339))
+	}
+
+	if len(q.Aggregations) > 0 {
+		return compileAggregations(q, plan)
+	}
+
+	if len(q.GroupBy) > 0 || q.Every != "" || q.Limit > 0 {
+		return nil, fmt.Errorf("group by, every and limit can only be used with aggregations")
+	}
+
+	if q.Where == nil {
+		return plan, nil
+	}
+
+	if c, ok := q.Where.(*Comparison); ok && strings.EqualFold(c.Field, traceIdField) {
+		if c.Op != Equal || c.Value.Type() != attribute.STRING {
+			return nil, fmt.Errorf("%s can only be compared with == and a string", traceIdField)
+		}
+
+		tid, err := trace.TraceIDFromHex(strings.ToLower(c.Value.AsString()))
+		if err != nil {
+			return nil, fmt.Errorf("%s %q is not a trace id: %w", traceIdField, c.Value.AsString(), err)
+		}
+		plan.TraceID = tid.String()
+		return plan, nil
+	}
+
+	where, err := compileTraceExpr(q.Where)
+	if err != nil {
+		return nil, err
+	}
+	plan.Where = where
+
+	return plan, nil
+}
+
+// compileAggregations treats the whole where clause as if it were in braces, as each span is
+// aggregated on its own
+func compileAggregations(q *Query, plan *Plan) (*Plan, error) {
+	for i, call := range q.Aggregations {
+		resolved, err := resolveCall(call)
+		if err != nil {
+			return nil, err
+		}
+		plan.Aggregations[i] = resolved
+	}
+
+	plan.GroupBy = q.GroupBy
+	plan.Limit = q.Limit
+	if plan.Limit == 0 {
+		plan.Limit = defaultGroupLimit
+	}
+
+	if q.Every != "" {
+		bucket, err := parseDuration(q.Every)
+		if err != nil {
+			return nil, fmt.Errorf("every: %w", err)
+		}
+		if bucket < time.Second || bucket%time.Second != 0 {
+			return nil, fmt.Errorf("every: %s must be a whole number of seconds", q.Every)
+		}
+		if plan.Range.Finish.Sub(plan.Range.Start)/bucket > maxBuckets {
+			return nil, fmt.Errorf("every: %s would need more than %d buckets", q.Every, maxBuckets)
+		}
+		plan.Bucket = bucket
+	}
+
+	if q.Where == nil {
+		return plan, nil
+	}
+
+	where, err := compileSpanExpr(q.Where)
+	if err != nil {
+		return nil, err
+	}
+	plan.SpanWhere = where
+
+	return plan, nil
+}
+
+// terms flattens a tree of the same binary operator into its operands
+func terms(expr Expr, op TokenKind) []Expr {
+	if b, ok := expr.(*BinaryExpr); ok && b.Op == op {
+		return append(terms(b.Left, op), terms(b.Right, op)...)
+	}
+	return []Expr{expr}
+}
+
+// compileTraceExpr converts an expression outside of braces, where each condition can be
+// matched by a different span in the trace.
+func compileTraceExpr(expr Expr) (storage.TraceExpr, error) {
+	switch e := expr.(type) {
+	case *BinaryExpr:
+		operands := terms(e, e.Op)
+		exprs := make([]storage.TraceExpr, len(operands))
+		for i, operand := range operands {
+			compiled, err := compileTraceExpr(operand)
+			if err != nil {
+				return nil, err
+			}
+			exprs[i] = compiled
+		}
+
+		if e.Op == And {
+			return storage.TraceAnd(exprs), nil
+		}
+		return storage.TraceOr(exprs), nil
+
+	case *NotExpr:
+		inner, err := compileTraceExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return storage.TraceNot{Expr: inner}, nil
+
+	case *SpanExpr:
+		inner, err := compileSpanExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter, ok := inner.(storage.SpanFilter); ok {
+			return filter, nil
+		}
+		return storage.AnySpan{Expr: inner}, nil
+
+	case *Comparison:
+		predicate, err := compilePredicate(e)
+		if err != nil {
+			return nil, err
+		}
+		return storage.SpanFilter{predicate}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported expression %s", expr)
+}
+
+// compileSpanExpr converts an expression inside braces, which must match on a single span.
+// Ands of plain predicates become a SpanFilter.
+func compileSpanExpr(expr Expr) (storage.SpanExpr, error) {
+	switch e := expr.(type) {
+	case *BinaryExpr:
+		operands := terms(e, e.Op)
+		exprs := make([]storage.SpanExpr, len(operands))
+		predicates := storage.SpanFilter{}
+		onlyPredicates := true
+
+		for i, operand := range operands {
+			compiled, err := compileSpanExpr(operand)
+			if err != nil {
+				return nil, err
+			}
+			exprs[i] = compiled
+
+			switch c := compiled.(type) {
+			case storage.Predicate:
+				predicates = append(predicates, c)
+			case storage.SpanFilter:
+				predicates = append(predicates, c...)
+			default:
+				onlyPredicates = false
+			}
+		}
+
+		if e.Op == Or {
+			return storage.SpanOr(exprs), nil
+		}
+		if onlyPredicates {
+			return predicates, nil
+		}
+		return storage.SpanAnd(exprs), nil
+
+	case *NotExpr:
+		// `not exists(field)` can be answered by a single predicate
+		if c, ok := e.Expr.(*Comparison); ok && c.Op == Exists {
+			predicate, err := compilePredicate(c)
+			if err != nil {
+				return nil, err
+			}
+			predicate.Op = storage.NotExists
+			return predicate, nil
+		}
+
+		inner, err := compileSpanExpr(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return storage.SpanNot{Expr: inner}, nil
+
+	case *SpanExpr:
+		return compileSpanExpr(e.Expr)
+
+	case *Comparison:
+		return compilePredicate(e)
+	}
+
+	return nil, fmt.Errorf("unsupported expression %s", expr)
+}
+
+var operators = map[TokenKind]storage.Operator{
+	Equal:        storage.Equal,
+	NotEqual:     storage.NotEqual,
+	Less:         storage.Less,
+	LessEqual:    storage.LessOrEqual,
+	Greater:      storage.Greater,
+	GreaterEqual: storage.GreaterOrEqual,
+	Exists:       storage.Exists,
+	StartsWith:   storage.StartsWith,
+	Contains:     storage.Contains,
+	Matches:      storage.Matches,
+	Glob:         storage.Glob,
+}
+
+func compilePredicate(c *Comparison) (storage.Predicate, error) {
+	if strings.EqualFold(c.Field, traceIdField) {
+		return storage.Predicate{}, fmt.Errorf("%s cannot be combined with other conditions", traceIdField)
+	}
+
+	var predicate storage.Predicate
+
+	switch {
+	case c.Length:
+		predicate = storage.CompareLength(c.Field, operators[c.Op], int(c.Value.AsInt64()))
+	case c.Quantifier == Any:
+		predicate = storage.IncludesAny(c.Field, c.Value)
+	case c.Quantifier == All:
+		predicate = storage.IncludesAll(c.Field, c.Value)
+	default:
+		switch c.Op {
+		case StartsWith, Matches, Glob:
+			if c.Value.Type() != attribute.STRING {
+				return storage.Predicate{}, fmt.Errorf("%s: %s needs a string pattern", c, c.Op)
+			}
+		}
+		predicate = storage.Compare(c.Field, operators[c.Op], c.Value)
+	}
+
+	scope, key := storage.ScopeOf(c.Field)
+	predicate.Key = attribute.Key(key)
+	predicate = predicate.In(scope)
+
+	if err := predicate.Validate(); err != nil {
+		return storage.Predicate{}, err
+	}
+	return predicate, nil
+}
+
+func resolveTime(ref *TimeRef, now time.Time) (time.Time, error) {
+	switch ref.Kind {
+	case Duration:
+		d, err := parseDuration(ref.Text)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+
+	case Clock:
+		return resolveClock(ref.Text, now)
+
+	case String:
+		return time.Parse(time.RFC3339, ref.Text)
+
+	case Number:
+		epoch, err := strconv.ParseInt(ref.Text, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(epoch, 0), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time %s", ref)
+}
+
+// parseDuration extends time.ParseDuration with a `d` suffix for days
+func parseDuration(text string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(text, "d"); found {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", text)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	return time.ParseDuration(text)
+}
+
+// resolveClock finds the most recent occurrence of a time of day, so `since 15:00` at 10:00
+// means 15:00 yesterday.
+func resolveClock(text string, now time.Time) (time.Time, error) {
+	var clock time.Time
+	var err error
+
+	if strings.Count(text, ":") == 2 {
+		clock, err = time.Parse("15:04:05", text)
+	} else {
+		clock, err = time.Parse("15:04", text)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time of day %q", text)
+	}
+
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location())
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+
+	return t, nil
+}
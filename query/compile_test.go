@@ -0,0 +1,376 @@
+package query
+
+import (
+	"romulus/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCompileTimes(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		Query  string
+		Start  time.Time
+		Finish time.Time
+	}{
+		{Query: ``, Start: now.Add(-time.Hour), Finish: now},
+		{Query: `since 15m`, Start: now.Add(-15 * time.Minute), Finish: now},
+		{Query: `since 2d`, Start: now.Add(-48 * time.Hour), Finish: now},
+		{Query: `since 09:00`, Start: time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC), Finish: now},
+		{Query: `since 15:00`, Start: time.Date(2025, 5, 31, 15, 0, 0, 0, time.UTC), Finish: now},
+		{Query: `since 2h until 1h`, Start: now.Add(-2 * time.Hour), Finish: now.Add(-time.Hour)},
+		{Query: `until 1748700000`, Start: time.Unix(1748700000, 0).Add(-time.Hour), Finish: time.Unix(1748700000, 0)},
+		{
+			Query:  `since "2025-06-01T08:00:00Z" until "2025-06-01T09:00:00Z"`,
+			Start:  time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC),
+			Finish: time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Query, func(t *testing.T) {
+			q, err := Parse(tc.Query)
+			require.NoError(t, err)
+
+			plan, err := Compile(q, now)
+			require.NoError(t, err)
+			require.True(t, tc.Start.Equal(plan.Range.Start), "start: %s", plan.Range.Start)
+			require.True(t, tc.Finish.Equal(plan.Range.Finish), "finish: %s", plan.Range.Finish)
+		})
+	}
+}
+
+func TestCompileFilters(t *testing.T) {
+	compile := func(t *testing.T, query string) *Plan {
+		q, err := Parse(query)
+		require.NoError(t, err)
+
+		plan, err := Compile(q, time.Now())
+		require.NoError(t, err)
+		return plan
+	}
+
+	t.Run("trace id", func(t *testing.T) {
+		plan := compile(t, `select trace where traceid == "AABBCCDD00000000000000000000EEFF"`)
+		require.Equal(t, "aabbccdd00000000000000000000eeff", plan.TraceID)
+		require.Nil(t, plan.Where)
+	})
+
+	t.Run("separate spans", func(t *testing.T) {
+		plan := compile(t, `where a = 1 and b = "two"`)
+		require.Equal(t, storage.TraceAnd{
+			storage.SpanFilter{storage.Is(attribute.Int64("a", 1))},
+			storage.SpanFilter{storage.Is(attribute.String("b", "two"))},
+		}, plan.Where)
+	})
+
+	t.Run("same span", func(t *testing.T) {
+		plan := compile(t, `where { a = 1 and b = "two" } and c = true`)
+		require.Equal(t, storage.TraceAnd{
+			storage.SpanFilter{storage.Is(attribute.Int64("a", 1)), storage.Is(attribute.String("b", "two"))},
+			storage.SpanFilter{storage.Is(attribute.Bool("c", true))},
+		}, plan.Where)
+	})
+
+	t.Run("operators", func(t *testing.T) {
+		plan := compile(t, `where { a != 1 and b < 2 and c <= 3 and d > 4 and e >= 5.5 and exists(f) and not exists(g) }`)
+		require.Equal(t, storage.SpanFilter{
+			storage.Compare("a", storage.NotEqual, attribute.Int64Value(1)),
+			storage.Compare("b", storage.Less, attribute.Int64Value(2)),
+			storage.Compare("c", storage.LessOrEqual, attribute.Int64Value(3)),
+			storage.Compare("d", storage.Greater, attribute.Int64Value(4)),
+			storage.Compare("e", storage.GreaterOrEqual, attribute.Float64Value(5.5)),
+			storage.Has("f"),
+			storage.Lacks("g"),
+		}, plan.Where)
+	})
+
+	t.Run("string matching", func(t *testing.T) {
+		plan := compile(t, `where { a startswith "/api" and b contains "x" and c matches "^e" and d glob "*.go" }`)
+		require.Equal(t, storage.SpanFilter{
+			storage.Match("a", storage.StartsWith, "/api"),
+			storage.Match("b", storage.Contains, "x"),
+			storage.Match("c", storage.Matches, "^e"),
+			storage.Match("d", storage.Glob, "*.go"),
+		}, plan.Where)
+	})
+
+	t.Run("slices", func(t *testing.T) {
+		plan := compile(t, `where { a contains 1 and b contains any ("x", "y") and c contains all (true) and len(d) < 3 }`)
+		require.Equal(t, storage.SpanFilter{
+			storage.Includes("a", attribute.Int64Value(1)),
+			storage.IncludesAny("b", attribute.StringSliceValue([]string{"x", "y"})),
+			storage.IncludesAll("c", attribute.BoolSliceValue([]bool{true})),
+			storage.CompareLength("d", storage.Less, 3),
+		}, plan.Where)
+	})
+
+	t.Run("intrinsics", func(t *testing.T) {
+		plan := compile(t, `where { status = "error" and kind = "server" and not exists(scope.name) and http.method = "GET" }`)
+		require.Equal(t, storage.SpanFilter{
+			storage.Is(attribute.String("status", "error")).In(storage.IntrinsicScope),
+			storage.Is(attribute.String("kind", "server")).In(storage.IntrinsicScope),
+			storage.Lacks("scope.name").In(storage.IntrinsicScope),
+			storage.Is(attribute.String("http.method", "GET")),
+		}, plan.Where)
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		plan := compile(t, `where { span.http.method = "GET" and resource.service.name = "api" and event.exception.type = "Timeout" and link.kind = "follows" and span.status = 200 }`)
+		require.Equal(t, storage.SpanFilter{
+			storage.Is(attribute.String("http.method", "GET")).In(storage.SpanScope),
+			storage.Is(attribute.String("service.name", "api")).In(storage.ResourceScope),
+			storage.Is(attribute.String("exception.type", "Timeout")).In(storage.EventScope),
+			storage.Is(attribute.String("kind", "follows")).In(storage.LinkScope),
+			storage.Is(attribute.Int64("status", 200)).In(storage.SpanScope),
+		}, plan.Where)
+	})
+
+	t.Run("event name", func(t *testing.T) {
+		plan := compile(t, `where event.name = "exception" and event.exception.type = "TimeoutError"`)
+		require.Equal(t, storage.TraceAnd{
+			storage.SpanFilter{storage.Is(attribute.String("name", "exception")).In(storage.EventIntrinsicScope)},
+			storage.SpanFilter{storage.Is(attribute.String("exception.type", "TimeoutError")).In(storage.EventScope)},
+		}, plan.Where)
+	})
+
+	t.Run("or", func(t *testing.T) {
+		plan := compile(t, `where a = 1 or b = 2 or c = 3`)
+		require.Equal(t, storage.TraceOr{
+			storage.SpanFilter{storage.Is(attribute.Int64("a", 1))},
+			storage.SpanFilter{storage.Is(attribute.Int64("b", 2))},
+			storage.SpanFilter{storage.Is(attribute.Int64("c", 3))},
+		}, plan.Where)
+	})
+
+	t.Run("not", func(t *testing.T) {
+		plan := compile(t, `where not a = 1`)
+		require.Equal(t, storage.TraceNot{
+			Expr: storage.SpanFilter{storage.Is(attribute.Int64("a", 1))},
+		}, plan.Where)
+	})
+
+	t.Run("same span or", func(t *testing.T) {
+		plan := compile(t, `where { a = 1 and (b = 2 or not c = 3) }`)
+		require.Equal(t, storage.AnySpan{Expr: storage.SpanAnd{
+			storage.Is(attribute.Int64("a", 1)),
+			storage.SpanOr{
+				storage.Is(attribute.Int64("b", 2)),
+				storage.SpanNot{Expr: storage.Is(attribute.Int64("c", 3))},
+			},
+		}}, plan.Where)
+	})
+
+	t.Run("nested filter and", func(t *testing.T) {
+		expected := storage.SpanAnd{
+			storage.SpanFilter{storage.Is(attribute.Int64("a", 1)), storage.Is(attribute.Int64("b", 2))},
+			storage.SpanOr{
+				storage.Is(attribute.Int64("c", 1)),
+				storage.Is(attribute.Int64("c", 2)),
+			},
+		}
+
+		plan := compile(t, `select traces where { {a == 1 and b == 2} and (c == 1 or c == 2) }`)
+		require.Equal(t, storage.AnySpan{Expr: expected}, plan.Where)
+
+		plan = compile(t, `count() where {a == 1 and b == 2} and (c == 1 or c == 2)`)
+		require.Equal(t, expected, plan.SpanWhere)
+	})
+
+	t.Run("aggregations", func(t *testing.T) {
+		plan := compile(t, `distinct_count() where a = 1`)
+		require.Equal(t, []Call{{Name: "distinct_count"}}, plan.Aggregations)
+		require.Nil(t, plan.Where)
+		require.Equal(t, storage.Is(attribute.Int64("a", 1)), plan.SpanWhere)
+	})
+
+	t.Run("aggregations on the same span", func(t *testing.T) {
+		plan := compile(t, `count() where a = 1 and b = 2`)
+		require.Equal(t, storage.SpanFilter{
+			storage.Is(attribute.Int64("a", 1)),
+			storage.Is(attribute.Int64("b", 2)),
+		}, plan.SpanWhere)
+	})
+
+	t.Run("group by", func(t *testing.T) {
+		plan := compile(t, `count() group by service.name every 5m`)
+		require.Equal(t, []string{"service.name"}, plan.GroupBy)
+		require.Equal(t, 5*time.Minute, plan.Bucket)
+		require.Equal(t, defaultGroupLimit, plan.Limit)
+
+		plan = compile(t, `count() group by a limit 3`)
+		require.Equal(t, 3, plan.Limit)
+		require.Zero(t, plan.Bucket)
+	})
+
+	t.Run("percentiles default to duration", func(t *testing.T) {
+		plan := compile(t, `p99(), p50(latency)`)
+		require.Equal(t, []Call{{Name: "p99", Field: "duration_ms"}, {Name: "p50", Field: "latency"}}, plan.Aggregations)
+	})
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []string{
+		`where traceid = "aa" or a = 1`,
+		`where a startswith 1`,
+		`median(a)`,
+		`sum()`,
+		`where a = 1 group by b`,
+		`count() every 1500ms`,
+		`count() every 1s since 7d`,
+		`where traceid = 1`,
+		`where traceid = "9"`,
+		`where traceid = ""`,
+		`where traceid = "zzbbccdd00000000000000000000eeff"`,
+		`where traceid = "00000000000000000000000000000000"`,
+		`where traceid = "aa" and a = 1`,
+		`since 1h until 2h`,
+		`since 25:00`,
+		`where a matches "(unclosed"`,
+		`where { a = 1 and b matches "[" }`,
+		`count() where a matches "*"`,
+		`where a contains true and b > true`,
+	}
+
+	for _, query := range cases {
+		t.Run(query, func(t *testing.T) {
+			q, err := Parse(query)
+			require.NoError(t, err)
+
+			_, err = Compile(q, time.Now())
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	run, tid := executor(t)
+
+	t.Run("by trace id", func(t *testing.T) {
+		result := run(t, `select trace where traceid == "`+tid.String()+`"`)
+		require.Len(t, result.Spans, 2)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+	})
+
+	t.Run("by attribute", func(t *testing.T) {
+		result := run(t, `select traces where { http.status_code = 500 and retry = true } since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+	})
+
+	t.Run("all traces", func(t *testing.T) {
+		result := run(t, `select traces since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+	})
+
+	t.Run("by comparison", func(t *testing.T) {
+		result := run(t, `select traces where http.status_code >= 500 since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where http.status_code < 500 since 15m`)
+		require.Empty(t, result.TraceIDs)
+	})
+
+	t.Run("by string match", func(t *testing.T) {
+		result := run(t, `select traces where name startswith "chi" since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where name glob "r*t" since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where name matches "^x" since 15m`)
+		require.Empty(t, result.TraceIDs)
+	})
+
+	t.Run("by slice", func(t *testing.T) {
+		result := run(t, `select traces where messaging.destinations contains "orders" since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where messaging.destinations contains all ("orders", "audit") since 15m`)
+		require.Empty(t, result.TraceIDs)
+
+		result = run(t, `select traces where len(messaging.destinations) = 2 since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+	})
+
+	t.Run("by duration", func(t *testing.T) {
+		result := run(t, `select traces where duration_ms > 2.5s since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where { duration_ms <= 1s and name = "root" } since 15m`)
+		require.Empty(t, result.TraceIDs)
+	})
+
+	t.Run("by intrinsic", func(t *testing.T) {
+		result := run(t, `select traces where { root = false and name = "child" } since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where status = "error" since 15m`)
+		require.Empty(t, result.TraceIDs)
+	})
+
+	t.Run("by or", func(t *testing.T) {
+		result := run(t, `select traces where http.status_code = 404 or retry = true since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `select traces where { http.status_code = 404 or retry = false } since 15m`)
+		require.Empty(t, result.TraceIDs)
+	})
+
+	t.Run("by nested filter and or", func(t *testing.T) {
+		result := run(t, `select traces where { {http.status_code = 500 and retry = true} and (name = "root" or name = "other") } since 15m`)
+		require.Empty(t, result.TraceIDs)
+
+		result = run(t, `select traces where { {http.status_code = 500 and retry = true} and (name = "child" or name = "other") } since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+
+		result = run(t, `count() where {http.status_code = 500 and retry = true} and (name = "root" or name = "other") since 15m`)
+		require.Equal(t, 0.0, *result.Aggregates[0].Value)
+
+		result = run(t, `count() where {http.status_code = 500 and retry = true} and (name = "child" or name = "other") since 15m`)
+		require.Equal(t, 1.0, *result.Aggregates[0].Value)
+	})
+
+	t.Run("by not", func(t *testing.T) {
+		result := run(t, `select traces where not http.status_code = 500 since 15m`)
+		require.Empty(t, result.TraceIDs)
+
+		// the root span doesn't have a status code
+		result = run(t, `select traces where { not http.status_code = 500 } since 15m`)
+		require.Equal(t, []trace.TraceID{tid}, result.TraceIDs)
+	})
+
+	t.Run("outside time range", func(t *testing.T) {
+		result := run(t, `select traces where http.status_code = 500 since 5m`)
+		require.Empty(t, result.TraceIDs)
+	})
+}
+
+// executor stores the storage test spans, starting ten minutes ago, and returns a function
+// running queries against them along with the trace id of the spans
+func executor(t *testing.T) (func(t *testing.T, query string) *Result, trace.TraceID) {
+	now := time.Now()
+	spans := storage.NewTestSpans(now.Add(-10 * time.Minute))
+
+	backend := storage.NewMemoryBackend()
+	require.NoError(t, storage.NewWriter(backend, "testing").Write(t.Context(), spans))
+	reader := storage.NewReader(backend, "testing")
+
+	run := func(t *testing.T, query string) *Result {
+		q, err := Parse(query)
+		require.NoError(t, err)
+
+		plan, err := Compile(q, now)
+		require.NoError(t, err)
+
+		result, err := Execute(t.Context(), reader, plan)
+		require.NoError(t, err)
+		return result
+	}
+
+	return run, spans[0].SpanContext.TraceID()
+}
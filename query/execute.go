@@ -0,0 +1,59 @@
+package query
+
+import (
+	"context"
+	"romulus/domain"
+	"romulus/storage"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Result struct {
+	TraceIDs   []trace.TraceID
+	Spans      []*domain.Span
+	Aggregates []Aggregate
+	Series     []Series
+}
+
+// Execute runs a compiled plan.  A trace id lookup returns the spans of the trace, a search
+// returns the ids of the matching traces, and aggregations return one value per aggregation, or
+// a series per group when grouped or bucketed.
+func Execute(ctx context.Context, reader *storage.Reader, plan *Plan) (*Result, error) {
+	if len(plan.Aggregations) > 0 {
+		groups, err := aggregate(ctx, reader, plan)
+		if err != nil {
+			return nil, err
+		}
+
+		// a plain aggregation is a single group with a single point
+		if len(plan.GroupBy) == 0 && plan.Bucket == 0 {
+			return &Result{Aggregates: groups[0].Points[0].Aggregates}, nil
+		}
+		return &Result{Series: groups}, nil
+	}
+
+	if plan.TraceID != "" {
+		spans, err := reader.Trace(ctx, plan.TraceID)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &Result{Spans: spans}
+		if len(spans) > 0 {
+			result.TraceIDs = []trace.TraceID{spans[0].SpanContext.TraceID()}
+		}
+		return result, nil
+	}
+
+	exprs := []storage.TraceExpr{}
+	if plan.Where != nil {
+		exprs = append(exprs, plan.Where)
+	}
+
+	traceIds, err := reader.Filter(ctx, plan.Range, exprs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{TraceIDs: traceIds}, nil
+}
@@ -0,0 +1,306 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	Ident
+	String
+	Number
+	Duration
+	Clock
+
+	Equal
+	NotEqual
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+
+	And
+	Or
+	Not
+
+	LeftParen
+	RightParen
+	LeftBrace
+	RightBrace
+	Comma
+
+	Select
+	Where
+	Since
+	Until
+	True
+	False
+	Exists
+	StartsWith
+	Contains
+	Matches
+	Glob
+	Any
+	All
+	Len
+	Group
+	By
+	Every
+	Limit
+)
+
+var keywords = map[string]TokenKind{
+	"select": Select,
+	"where":  Where,
+	"since":  Since,
+	"until":  Until,
+	"and":    And,
+	"or":     Or,
+	"not":    Not,
+	"true":   True,
+	"false":  False,
+	"exists": Exists,
+
+	"startswith": StartsWith,
+	"contains":   Contains,
+	"matches":    Matches,
+	"glob":       Glob,
+	"any":        Any,
+	"all":        All,
+	"len":        Len,
+	"group":      Group,
+	"by":         By,
+	"every":      Every,
+	"limit":      Limit,
+}
+
+var tokenNames = map[TokenKind]string{
+	EOF:          "end of query",
+	Ident:        "identifier",
+	String:       "string",
+	Number:       "number",
+	Duration:     "duration",
+	Clock:        "time of day",
+	Equal:        "==",
+	NotEqual:     "!=",
+	Less:         "<",
+	LessEqual:    "<=",
+	Greater:      ">",
+	GreaterEqual: ">=",
+	And:          "and",
+	Or:           "or",
+	Not:          "not",
+	LeftParen:    "(",
+	RightParen:   ")",
+	LeftBrace:    "{",
+	RightBrace:   "}",
+	Comma:        ",",
+	Select:       "select",
+	Where:        "where",
+	Since:        "since",
+	Until:        "until",
+	True:         "true",
+	False:        "false",
+	Exists:       "exists",
+	StartsWith:   "startswith",
+	Contains:     "contains",
+	Matches:      "matches",
+	Glob:         "glob",
+	Any:          "any",
+	All:          "all",
+	Len:          "len",
+	Group:        "group",
+	By:           "by",
+	Every:        "every",
+	Limit:        "limit",
+}
+
+func (k TokenKind) String() string {
+	return tokenNames[k]
+}
+
+type Token struct {
+	Kind TokenKind
+	// Text is the token as written, except for strings where it is the unquoted value
+	Text string
+	Pos  int
+}
+
+// Lex splits a query into tokens, always ending with an EOF token
+func Lex(input string) ([]Token, error) {
+	l := &lexer{input: []rune(input)}
+
+	tokens := []Token{}
+	for {
+		token, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+		if token.Kind == EOF {
+			return tokens, nil
+		}
+	}
+}
+
+type lexer struct {
+	input []rune
+	pos   int
+}
+
+func (l *lexer) peek(offset int) rune {
+	if l.pos+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+offset]
+}
+
+func (l *lexer) next() (Token, error) {
+	for unicode.IsSpace(l.peek(0)) {
+		l.pos++
+	}
+
+	start := l.pos
+	r := l.peek(0)
+
+	symbol := func(kind TokenKind, width int) (Token, error) {
+		l.pos += width
+		return Token{Kind: kind, Text: string(l.input[start:l.pos]), Pos: start}, nil
+	}
+
+	switch {
+	case l.pos >= len(l.input):
+		return Token{Kind: EOF, Pos: start}, nil
+
+	case r == '(':
+		return symbol(LeftParen, 1)
+	case r == ')':
+		return symbol(RightParen, 1)
+	case r == '{':
+		return symbol(LeftBrace, 1)
+	case r == '}':
+		return symbol(RightBrace, 1)
+	case r == ',':
+		return symbol(Comma, 1)
+
+	case r == '=' && l.peek(1) == '=':
+		return symbol(Equal, 2)
+	case r == '=':
+		return symbol(Equal, 1)
+	case r == '!' && l.peek(1) == '=':
+		return symbol(NotEqual, 2)
+	case r == '!':
+		return symbol(Not, 1)
+	case r == '<' && l.peek(1) == '=':
+		return symbol(LessEqual, 2)
+	case r == '<':
+		return symbol(Less, 1)
+	case r == '>' && l.peek(1) == '=':
+		return symbol(GreaterEqual, 2)
+	case r == '>':
+		return symbol(Greater, 1)
+	case r == '&' && l.peek(1) == '&':
+		return symbol(And, 2)
+	case r == '|' && l.peek(1) == '|':
+		return symbol(Or, 2)
+
+	case r == '"' || r == '\'':
+		return l.string(r)
+
+	case unicode.IsDigit(r) || (r == '-' && unicode.IsDigit(l.peek(1))):
+		return l.number()
+
+	case isIdentStart(r):
+		for isIdentPart(l.peek(0)) {
+			l.pos++
+		}
+
+		text := string(l.input[start:l.pos])
+		if kind, found := keywords[strings.ToLower(text)]; found {
+			return Token{Kind: kind, Text: text, Pos: start}, nil
+		}
+		return Token{Kind: Ident, Text: text, Pos: start}, nil
+	}
+
+	return Token{}, &ParseError{Pos: start, Message: fmt.Sprintf("unexpected character %q", r)}
+}
+
+func (l *lexer) string(quote rune) (Token, error) {
+	start := l.pos
+	l.pos++
+
+	sb := strings.Builder{}
+	for {
+		r := l.peek(0)
+		switch {
+		case l.pos >= len(l.input):
+			return Token{}, &ParseError{Pos: start, Message: "unterminated string"}
+
+		case r == '\\':
+			escaped := l.peek(1)
+			switch escaped {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				sb.WriteRune(escaped)
+			}
+			l.pos += 2
+
+		case r == quote:
+			l.pos++
+			return Token{Kind: String, Text: sb.String(), Pos: start}, nil
+
+		default:
+			sb.WriteRune(r)
+			l.pos++
+		}
+	}
+}
+
+// number lexes plain numbers (`200`, `-1.5`), durations (`15m`, `1h30m`, `250ms`) and
+// times of day (`15:00`, `09:30:15`)
+func (l *lexer) number() (Token, error) {
+	start := l.pos
+	l.pos++
+
+	digits := func() {
+		for unicode.IsDigit(l.peek(0)) || l.peek(0) == '.' {
+			l.pos++
+		}
+	}
+
+	digits()
+	kind := Number
+
+	switch {
+	case l.peek(0) == ':':
+		kind = Clock
+		for l.peek(0) == ':' && unicode.IsDigit(l.peek(1)) {
+			l.pos++
+			digits()
+		}
+
+	case unicode.IsLetter(l.peek(0)):
+		kind = Duration
+		for unicode.IsLetter(l.peek(0)) || unicode.IsDigit(l.peek(0)) || l.peek(0) == '.' {
+			l.pos++
+		}
+	}
+
+	return Token{Kind: kind, Text: string(l.input[start:l.pos]), Pos: start}, nil
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
@@ -0,0 +1,514 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Parse reads a query, for example
+//
+//	distinct_count() where http.status_code == 200 since 15:00
+//	select trace where traceid == "aabbccdd"
+//	select traces where { name == "GET" and span.http.path == "/" } since 15m
+func Parse(input string) (*Query, error) {
+	tokens, err := Lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	return p.query()
+}
+
+// ParseTimeRef reads a time on its own, in any of the forms accepted by since and until.  An
+// unquoted RFC3339 timestamp is also accepted, as quoting is awkward on the command line.
+func ParseTimeRef(input string) (*TimeRef, error) {
+	if _, err := time.Parse(time.RFC3339, input); err == nil {
+		return &TimeRef{Kind: String, Text: input}, nil
+	}
+
+	tokens, err := Lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	ref, err := p.timeRef()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(EOF); err != nil {
+		return nil, err
+	}
+
+	return ref, nil
+}
+
+type parser struct {
+	tokens []Token
+	pos    int
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) advance() Token {
+	token := p.tokens[p.pos]
+	if token.Kind != EOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *parser) accept(kinds ...TokenKind) (Token, bool) {
+	token := p.peek()
+	for _, kind := range kinds {
+		if token.Kind == kind {
+			return p.advance(), true
+		}
+	}
+	return token, false
+}
+
+func (p *parser) expect(kind TokenKind) (Token, error) {
+	token, ok := p.accept(kind)
+	if !ok {
+		return token, p.unexpected(token, kind.String())
+	}
+	return token, nil
+}
+
+func (p *parser) unexpected(token Token, expected string) error {
+	found := token.Kind.String()
+	if token.Kind != EOF {
+		found = fmt.Sprintf("%q", token.Text)
+	}
+	return &ParseError{Pos: token.Pos, Message: fmt.Sprintf("expected %s, found %s", expected, found)}
+}
+
+func (p *parser) query() (*Query, error) {
+	q := &Query{}
+
+	if _, ok := p.accept(Select); ok {
+		if err := p.selection(q); err != nil {
+			return nil, err
+		}
+	} else if p.peek().Kind == Ident {
+		// the select keyword is optional for aggregations: `count() where ...`
+		if err := p.selection(q); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := p.accept(Where); ok {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		q.Where = expr
+	}
+
+	for {
+		token, ok := p.accept(Since, Until, Group, Every, Limit)
+		if !ok {
+			break
+		}
+
+		if err := p.clause(q, token); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.expect(EOF); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// clause parses one of the clauses which can follow the where clause, in any order
+func (p *parser) clause(q *Query, token Token) error {
+	switch token.Kind {
+	case Since, Until:
+		ref, err := p.timeRef()
+		if err != nil {
+			return err
+		}
+
+		if token.Kind == Since {
+			q.Since = ref
+		} else {
+			q.Until = ref
+		}
+
+	case Group:
+		if _, err := p.expect(By); err != nil {
+			return err
+		}
+
+		for {
+			field, err := p.expect(Ident)
+			if err != nil {
+				return err
+			}
+			q.GroupBy = append(q.GroupBy, field.Text)
+
+			if _, ok := p.accept(Comma); !ok {
+				break
+			}
+		}
+
+	case Every:
+		width, err := p.expect(Duration)
+		if err != nil {
+			return err
+		}
+		q.Every = width.Text
+
+	case Limit:
+		n, err := p.expect(Number)
+		if err != nil {
+			return err
+		}
+
+		limit, err := strconv.Atoi(n.Text)
+		if err != nil || limit < 1 {
+			return &ParseError{Pos: n.Pos, Message: fmt.Sprintf("invalid limit %q", n.Text)}
+		}
+		q.Limit = limit
+	}
+
+	return nil
+}
+
+// selection is either a target (`trace` or `traces`), or a list of aggregation calls
+func (p *parser) selection(q *Query) error {
+	name, err := p.expect(Ident)
+	if err != nil {
+		return err
+	}
+
+	target := strings.ToLower(name.Text)
+	if p.peek().Kind != LeftParen && (target == "trace" || target == "traces") {
+		return nil
+	}
+
+	for {
+		call, err := p.call(name)
+		if err != nil {
+			return err
+		}
+		q.Aggregations = append(q.Aggregations, call)
+
+		if _, ok := p.accept(Comma); !ok {
+			return nil
+		}
+
+		if name, err = p.expect(Ident); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *parser) call(name Token) (Call, error) {
+	if _, err := p.expect(LeftParen); err != nil {
+		return Call{}, err
+	}
+
+	call := Call{Name: strings.ToLower(name.Text)}
+	if field, ok := p.accept(Ident); ok {
+		call.Field = field.Text
+	}
+
+	if _, err := p.expect(RightParen); err != nil {
+		return Call{}, err
+	}
+
+	return call, nil
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.accept(Or); !ok {
+			return left, nil
+		}
+
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: Or, Left: left, Right: right}
+	}
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.accept(And); !ok {
+			return left, nil
+		}
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: And, Left: left, Right: right}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	if _, ok := p.accept(Not); ok {
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	if _, ok := p.accept(LeftParen); ok {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RightParen); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+
+	if _, ok := p.accept(LeftBrace); ok {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RightBrace); err != nil {
+			return nil, err
+		}
+		return &SpanExpr{Expr: expr}, nil
+	}
+
+	if _, ok := p.accept(Exists); ok {
+		return p.exists()
+	}
+
+	if _, ok := p.accept(Len); ok {
+		return p.length()
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) exists() (Expr, error) {
+	if _, err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+
+	field, err := p.expect(Ident)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+
+	return &Comparison{Field: field.Text, Op: Exists}, nil
+}
+
+// length is `len(field) <op> <int>`
+func (p *parser) length() (Expr, error) {
+	if _, err := p.expect(LeftParen); err != nil {
+		return nil, err
+	}
+
+	field, err := p.expect(Ident)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(RightParen); err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept(Equal, NotEqual, Less, LessEqual, Greater, GreaterEqual)
+	if !ok {
+		return nil, p.unexpected(op, "a comparison operator")
+	}
+
+	token, err := p.expect(Number)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.ParseInt(token.Text, 10, 64)
+	if err != nil {
+		return nil, &ParseError{Pos: token.Pos, Message: fmt.Sprintf("invalid length %q", token.Text)}
+	}
+
+	return &Comparison{Field: field.Text, Op: op.Kind, Value: attribute.Int64Value(n), Length: true}, nil
+}
+
+func (p *parser) comparison() (Expr, error) {
+	field, err := p.expect(Ident)
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept(Equal, NotEqual, Less, LessEqual, Greater, GreaterEqual, StartsWith, Contains, Matches, Glob)
+	if !ok {
+		return nil, p.unexpected(op, "a comparison operator")
+	}
+
+	if op.Kind == Contains {
+		if quantifier, ok := p.accept(Any, All); ok {
+			values, err := p.list()
+			if err != nil {
+				return nil, err
+			}
+			return &Comparison{Field: field.Text, Op: op.Kind, Value: values, Quantifier: quantifier.Kind}, nil
+		}
+	}
+
+	value, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{Field: field.Text, Op: op.Kind, Value: value}, nil
+}
+
+// list is a parenthesised list of literals, which must all be the same type, except that ints
+// and floats can be mixed
+func (p *parser) list() (attribute.Value, error) {
+	start, err := p.expect(LeftParen)
+	if err != nil {
+		return attribute.Value{}, err
+	}
+
+	values := []attribute.Value{}
+	for {
+		value, err := p.literal()
+		if err != nil {
+			return attribute.Value{}, err
+		}
+		values = append(values, value)
+
+		if _, ok := p.accept(Comma); !ok {
+			break
+		}
+	}
+
+	if _, err := p.expect(RightParen); err != nil {
+		return attribute.Value{}, err
+	}
+
+	list, ok := toSlice(values)
+	if !ok {
+		return attribute.Value{}, &ParseError{Pos: start.Pos, Message: "list values must all be the same type"}
+	}
+	return list, nil
+}
+
+func toSlice(values []attribute.Value) (attribute.Value, bool) {
+	types := map[attribute.Type]bool{}
+	for _, v := range values {
+		types[v.Type()] = true
+	}
+
+	switch {
+	case len(types) == 1 && types[attribute.STRING]:
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = v.AsString()
+		}
+		return attribute.StringSliceValue(strs), true
+
+	case len(types) == 1 && types[attribute.BOOL]:
+		bools := make([]bool, len(values))
+		for i, v := range values {
+			bools[i] = v.AsBool()
+		}
+		return attribute.BoolSliceValue(bools), true
+
+	case len(types) == 1 && types[attribute.INT64]:
+		ints := make([]int64, len(values))
+		for i, v := range values {
+			ints[i] = v.AsInt64()
+		}
+		return attribute.Int64SliceValue(ints), true
+
+	case len(types) == 2 && types[attribute.INT64] && types[attribute.FLOAT64], len(types) == 1 && types[attribute.FLOAT64]:
+		floats := make([]float64, len(values))
+		for i, v := range values {
+			if v.Type() == attribute.INT64 {
+				floats[i] = float64(v.AsInt64())
+			} else {
+				floats[i] = v.AsFloat64()
+			}
+		}
+		return attribute.Float64SliceValue(floats), true
+	}
+
+	return attribute.Value{}, false
+}
+
+func (p *parser) literal() (attribute.Value, error) {
+	token := p.advance()
+
+	switch token.Kind {
+	case String:
+		return attribute.StringValue(token.Text), nil
+
+	case True, False:
+		return attribute.BoolValue(token.Kind == True), nil
+
+	case Number:
+		if i, err := strconv.ParseInt(token.Text, 10, 64); err == nil {
+			return attribute.Int64Value(i), nil
+		}
+
+		f, err := strconv.ParseFloat(token.Text, 64)
+		if err != nil {
+			return attribute.Value{}, &ParseError{Pos: token.Pos, Message: fmt.Sprintf("invalid number %q", token.Text)}
+		}
+		return attribute.Float64Value(f), nil
+
+	case Duration:
+		// durations are compared in milliseconds, to match duration_ms
+		d, err := parseDuration(token.Text)
+		if err != nil {
+			return attribute.Value{}, &ParseError{Pos: token.Pos, Message: fmt.Sprintf("invalid duration %q", token.Text)}
+		}
+		return attribute.Float64Value(float64(d) / float64(time.Millisecond)), nil
+	}
+
+	return attribute.Value{}, p.unexpected(token, "a string, number, duration or boolean")
+}
+
+func (p *parser) timeRef() (*TimeRef, error) {
+	token, ok := p.accept(Clock, Duration, String, Number)
+	if !ok {
+		return nil, p.unexpected(token, "a time, duration or timestamp")
+	}
+
+	return &TimeRef{Kind: token.Kind, Text: token.Text}, nil
+}
@@ -0,0 +1,162 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestLex(t *testing.T) {
+	tokens, err := Lex(`count() where a.b >= -1.5 && name != 'x' since 15:00 until 15m`)
+	require.NoError(t, err)
+
+	kinds := make([]TokenKind, len(tokens))
+	for i, token := range tokens {
+		kinds[i] = token.Kind
+	}
+
+	require.Equal(t, []TokenKind{
+		Ident, LeftParen, RightParen, Where,
+		Ident, GreaterEqual, Number, And, Ident, NotEqual, String,
+		Since, Clock, Until, Duration, EOF,
+	}, kinds)
+
+	require.Equal(t, "a.b", tokens[4].Text)
+	require.Equal(t, "-1.5", tokens[6].Text)
+	require.Equal(t, "x", tokens[10].Text)
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		Query    string
+		Expected string
+	}{
+		{
+			Query:    `distinct_count() where http.status_code == 200 since 15:00`,
+			Expected: `select distinct_count() where http.status_code == 200 since 15:00`,
+		},
+		{
+			Query:    `select trace where traceid == "aabbccdd"`,
+			Expected: `select traces where traceid == "aabbccdd"`,
+		},
+		{
+			Query:    `SELECT traces WHERE {span.name="GET" && span.http.path="/"} SINCE 1h`,
+			Expected: `select traces where { (span.name == "GET" and span.http.path == "/") } since 1h`,
+		},
+		{
+			Query:    `select count(), avg(duration_ms) where a = 1 or b = 2 and not c = true`,
+			Expected: `select count(), avg(duration_ms) where (a == 1 or (b == 2 and not c == true))`,
+		},
+		{
+			Query:    `where (a = 1 || b = 2) && c < 2.5 until "2025-06-01T15:00:00Z"`,
+			Expected: `select traces where ((a == 1 or b == 2) and c < 2.5) until "2025-06-01T15:00:00Z"`,
+		},
+		{
+			Query:    `where http.route startswith "/api" and db.statement CONTAINS "orders" or msg matches "^err" or path glob "/v?/*"`,
+			Expected: `select traces where (((http.route startswith "/api" and db.statement contains "orders") or msg matches "^err") or path glob "/v?/*")`,
+		},
+		{
+			Query:    `where queues contains any ("a", "b") and ports contains all (80, 443.5) and len(queues) >= 2 and ports contains 80`,
+			Expected: `select traces where (((queues contains any ("a", "b") and ports contains all (80, 443.5)) and len(queues) >= 2) and ports contains 80)`,
+		},
+		{
+			Query:    `p99() where a = 1 since 1h group by service.name, http.route limit 5 every 1m`,
+			Expected: `select p99() where a == 1 group by service.name, http.route every 1m limit 5 since 1h`,
+		},
+		{
+			Query:    `where duration_ms > 2s and duration_ms < 1m30s and duration_ms >= 250`,
+			Expected: `select traces where ((duration_ms > 2000 and duration_ms < 90000) and duration_ms >= 250)`,
+		},
+		{
+			Query:    `where exists(a.b) and not exists(c)`,
+			Expected: `select traces where (exists(a.b) and not exists(c))`,
+		},
+		{
+			Query:    ``,
+			Expected: `select traces`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Query, func(t *testing.T) {
+			q, err := Parse(tc.Query)
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, q.String())
+		})
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	q, err := Parse(`where a = 1 and b = 1.5 and c = "s" and d = false`)
+	require.NoError(t, err)
+
+	values := []attribute.Value{}
+	for _, term := range terms(q.Where, And) {
+		values = append(values, term.(*Comparison).Value)
+	}
+
+	require.Equal(t, []attribute.Value{
+		attribute.Int64Value(1),
+		attribute.Float64Value(1.5),
+		attribute.StringValue("s"),
+		attribute.BoolValue(false),
+	}, values)
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		Query string
+		Error string
+	}{
+		{Query: `where a =`, Error: "position 9: expected a string, number, duration or boolean, found end of query"},
+		{Query: `where a 1`, Error: `position 8: expected a comparison operator, found "1"`},
+		{Query: `where (a = 1`, Error: "position 12: expected ), found end of query"},
+		{Query: `where a = "open`, Error: "position 10: unterminated string"},
+		{Query: `where a = 1 since`, Error: "position 17: expected a time, duration or timestamp, found end of query"},
+		{Query: `count( where`, Error: `position 7: expected ), found "where"`},
+		{Query: `where a contains any (1, "b")`, Error: "position 21: list values must all be the same type"},
+		{Query: `where len(a) > 1.5`, Error: `position 15: invalid length "1.5"`},
+		{Query: `count() group service.name`, Error: `position 14: expected by, found "service.name"`},
+		{Query: `count() limit 0`, Error: `position 14: invalid limit "0"`},
+		{Query: `count() every 15`, Error: `position 14: expected duration, found "15"`},
+		{Query: `where duration_ms > 2parsecs`, Error: `position 20: invalid duration "2parsecs"`},
+		{Query: `where a = 1 #`, Error: `position 12: unexpected character '#'`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Query, func(t *testing.T) {
+			_, err := Parse(tc.Query)
+			require.EqualError(t, err, tc.Error)
+		})
+	}
+}
+
+func TestParseTimeRef(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Expected *TimeRef
+	}{
+		{"15m", &TimeRef{Kind: Duration, Text: "15m"}},
+		{"7d", &TimeRef{Kind: Duration, Text: "7d"}},
+		{"15:00", &TimeRef{Kind: Clock, Text: "15:00"}},
+		{"1748790000", &TimeRef{Kind: Number, Text: "1748790000"}},
+		{"2025-06-01T15:00:00Z", &TimeRef{Kind: String, Text: "2025-06-01T15:00:00Z"}},
+		{`"2025-06-01T15:00:00Z"`, &TimeRef{Kind: String, Text: "2025-06-01T15:00:00Z"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Input, func(t *testing.T) {
+			ref, err := ParseTimeRef(tc.Input)
+			require.NoError(t, err)
+			require.Equal(t, tc.Expected, ref)
+		})
+	}
+
+	for _, input := range []string{"", "yesterday", "15m 10m", "2025-06-01"} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			_, err := ParseTimeRef(input)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package receiver
+
+import (
+	"context"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var _ collectortrace.TraceServiceServer = &TraceService{}
+
+// TraceService implements the OTLP gRPC TraceService/Export endpoint
+type TraceService struct {
+	collectortrace.UnimplementedTraceServiceServer
+
+	writer SpanWriter
+}
+
+func NewTraceService(writer SpanWriter) *TraceService {
+	return &TraceService{
+		writer: writer,
+	}
+}
+
+// NewGRPCServer creates a grpc server with the TraceService registered
+func NewGRPCServer(writer SpanWriter, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	collectortrace.RegisterTraceServiceServer(server, NewTraceService(writer))
+
+	return server
+}
+
+func (s *TraceService) Export(ctx context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	response, err := export(ctx, s.writer, req)
+	if err != nil {
+		return nil, status.Error(errorCode(err), err.Error())
+	}
+
+	return response, nil
+}
@@ -0,0 +1,77 @@
+package receiver
+
+import (
+	"context"
+	"net"
+	"romulus/domain"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type memoryWriter struct {
+	mu    sync.Mutex
+	spans []domain.Span
+}
+
+func (w *memoryWriter) Write(ctx context.Context, spans []domain.Span) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.spans = append(w.spans, spans...)
+	return nil
+}
+
+func exportRequest() []*tracepb.ResourceSpans {
+	return []*tracepb.ResourceSpans{{
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Spans: []*tracepb.Span{
+				{
+					TraceId: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+					SpanId:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+					Name:    "valid",
+				},
+				{
+					TraceId: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+					Name:    "no span id",
+				},
+			},
+		}},
+	}}
+}
+
+func TestGRPCExport(t *testing.T) {
+	writer := &memoryWriter{}
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := NewGRPCServer(writer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(t.Context(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	client := collectortrace.NewTraceServiceClient(conn)
+	response, err := client.Export(t.Context(), &collectortrace.ExportTraceServiceRequest{
+		ResourceSpans: exportRequest(),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, writer.spans, 1)
+	require.Equal(t, "valid", writer.spans[0].Name)
+
+	require.NotNil(t, response.PartialSuccess)
+	require.EqualValues(t, 1, response.PartialSuccess.RejectedSpans)
+	require.Contains(t, response.PartialSuccess.ErrorMessage, "no span id")
+}
@@ -0,0 +1,130 @@
+package receiver
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"romulus/otlp"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+
+	// the limit is applied after decompression
+	maxRequestBytes = 32 * 1024 * 1024
+)
+
+// HTTPHandler implements the OTLP/HTTP `POST /v1/traces` endpoint, for both the protobuf and
+// json encodings, optionally gzip compressed.
+type HTTPHandler struct {
+	writer SpanWriter
+}
+
+func NewHTTPHandler(writer SpanWriter) *HTTPHandler {
+	return &HTTPHandler{
+		writer: writer,
+	}
+}
+
+// Register adds the OTLP routes to a mux
+func (h *HTTPHandler) Register(mux *http.ServeMux) {
+	mux.Handle("POST /v1/traces", h)
+}
+
+func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != contentTypeProtobuf && contentType != contentTypeJSON) {
+		writeStatus(w, contentTypeJSON, http.StatusUnsupportedMediaType, codes.InvalidArgument, fmt.Sprintf("unsupported content type %q", r.Header.Get("Content-Type")))
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		writeStatus(w, contentType, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+
+	req := &collectortrace.ExportTraceServiceRequest{}
+	if contentType == contentTypeProtobuf {
+		err = proto.Unmarshal(body, req)
+	} else {
+		err = otlp.UnmarshalJSON(body, req)
+	}
+	if err != nil {
+		writeStatus(w, contentType, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+
+	response, err := export(r.Context(), h.writer, req)
+	if err != nil {
+		code := errorCode(err)
+		httpStatus := http.StatusServiceUnavailable
+		if code == codes.InvalidArgument {
+			httpStatus = http.StatusBadRequest
+		}
+		writeStatus(w, contentType, httpStatus, code, err.Error())
+		return
+	}
+
+	writeMessage(w, contentType, http.StatusOK, response)
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	content, err := io.ReadAll(io.LimitReader(body, maxRequestBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxRequestBytes {
+		return nil, fmt.Errorf("request body is larger than %d bytes", maxRequestBytes)
+	}
+
+	return content, nil
+}
+
+// errors are reported as a google.rpc.Status message, in the same encoding as the request
+func writeStatus(w http.ResponseWriter, contentType string, httpStatus int, code codes.Code, message string) {
+	writeMessage(w, contentType, httpStatus, status.New(code, message).Proto())
+}
+
+func writeMessage(w http.ResponseWriter, contentType string, httpStatus int, message proto.Message) {
+	var body []byte
+	var err error
+
+	if contentType == contentTypeProtobuf {
+		body, err = proto.Marshal(message)
+	} else {
+		body, err = protojson.Marshal(message)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+}
@@ -0,0 +1,140 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"romulus/domain"
+	"romulus/storage"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestServer(t *testing.T, writer SpanWriter) *httptest.Server {
+	mux := http.NewServeMux()
+	NewHTTPHandler(writer).Register(mux)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write(ctx context.Context, spans []domain.Span) error {
+	return w.err
+}
+
+func TestHTTPExportProtobuf(t *testing.T) {
+	writer := &memoryWriter{}
+	server := newTestServer(t, writer)
+
+	body, err := proto.Marshal(&collectortrace.ExportTraceServiceRequest{ResourceSpans: exportRequest()})
+	require.NoError(t, err)
+
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	gz.Write(body)
+	gz.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/traces", compressed)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/x-protobuf", res.Header.Get("Content-Type"))
+
+	content, _ := io.ReadAll(res.Body)
+	response := &collectortrace.ExportTraceServiceResponse{}
+	require.NoError(t, proto.Unmarshal(content, response))
+
+	require.EqualValues(t, 1, response.PartialSuccess.RejectedSpans)
+	require.Len(t, writer.spans, 1)
+}
+
+func TestHTTPExportJSON(t *testing.T) {
+	writer := &memoryWriter{}
+	server := newTestServer(t, writer)
+
+	body := `{"resourceSpans":[{"scopeSpans":[{"spans":[{
+		"traceId": "5b8efff798038103d269b633813fc60c",
+		"spanId": "eee19b7ec3c1b174",
+		"name": "from json"
+	}]}]}]}`
+
+	res, err := http.Post(server.URL+"/v1/traces", "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	content, _ := io.ReadAll(res.Body)
+	response := &collectortrace.ExportTraceServiceResponse{}
+	require.NoError(t, protojson.Unmarshal(content, response))
+	require.Nil(t, response.PartialSuccess)
+
+	require.Len(t, writer.spans, 1)
+	require.Equal(t, "from json", writer.spans[0].Name)
+}
+
+func TestHTTPExportErrors(t *testing.T) {
+	server := newTestServer(t, &memoryWriter{})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		res, err := http.Post(server.URL+"/v1/traces", "text/plain", bytes.NewBufferString("hello"))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+	})
+
+	t.Run("invalid body", func(t *testing.T) {
+		res, err := http.Post(server.URL+"/v1/traces", "application/json", bytes.NewBufferString("{"))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("spans which can never be stored", func(t *testing.T) {
+		server := newTestServer(t, failingWriter{fmt.Errorf("%w: NaN", storage.ErrInvalidSpan)})
+		body, _ := proto.Marshal(&collectortrace.ExportTraceServiceRequest{ResourceSpans: exportRequest()})
+
+		res, err := http.Post(server.URL+"/v1/traces", "application/x-protobuf", bytes.NewReader(body))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("storage unavailable", func(t *testing.T) {
+		server := newTestServer(t, failingWriter{errors.New("connection refused")})
+		body, _ := proto.Marshal(&collectortrace.ExportTraceServiceRequest{ResourceSpans: exportRequest()})
+
+		res, err := http.Post(server.URL+"/v1/traces", "application/x-protobuf", bytes.NewReader(body))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/v1/traces")
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+}
@@ -0,0 +1,47 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"romulus/domain"
+	"romulus/otlp"
+	"romulus/storage"
+
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc/codes"
+)
+
+// SpanWriter is where received spans are handed to, normally a storage.Writer
+type SpanWriter interface {
+	Write(ctx context.Context, spans []domain.Span) error
+}
+
+// export is shared by all receivers: the spans are converted and written, and any which could
+// not be converted are reported as a partial success.  An error means some of the spans may not
+// have been stored, and errorCode says whether the client should retry.
+func export(ctx context.Context, writer SpanWriter, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	spans, rejected, convertErr := otlp.ToSpans(req.GetResourceSpans())
+
+	if err := writer.Write(ctx, spans); err != nil {
+		return nil, err
+	}
+
+	response := &collectortrace.ExportTraceServiceResponse{}
+	if rejected > 0 {
+		response.PartialSuccess = &collectortrace.ExportTracePartialSuccess{
+			RejectedSpans: int64(rejected),
+			ErrorMessage:  convertErr.Error(),
+		}
+	}
+
+	return response, nil
+}
+
+// errorCode is the status for a failed export.  Spans which can never be stored are the
+// client's fault, and retrying them would fail forever, so only other errors are retryable.
+func errorCode(err error) codes.Code {
+	if errors.Is(err, storage.ErrInvalidSpan) || errors.Is(err, storage.ErrInvalidKey) {
+		return codes.InvalidArgument
+	}
+	return codes.Unavailable
+}
@@ -0,0 +1,107 @@
+package receiver
+
+import (
+	"bytes"
+	"net/http"
+	"romulus/otlp"
+	"romulus/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRoundTripThroughStorage(t *testing.T) {
+	start := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	tid := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	str := func(key, value string) *commonpb.KeyValue {
+		return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+	}
+	integer := func(key string, value int64) *commonpb.KeyValue {
+		return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+	}
+	span := func(sid byte, parent []byte, name string) *tracepb.Span {
+		return &tracepb.Span{
+			TraceId:           tid,
+			SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, sid},
+			ParentSpanId:      parent,
+			Name:              name,
+			Kind:              tracepb.Span_SPAN_KIND_SERVER,
+			StartTimeUnixNano: uint64(start.Add(time.Duration(sid) * time.Millisecond).UnixNano()),
+			EndTimeUnixNano:   uint64(start.Add(time.Second).UnixNano()),
+			Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+		}
+	}
+
+	root := span(1, nil, "root")
+
+	child := span(2, root.SpanId, "child")
+	child.Attributes = []*commonpb.KeyValue{str("http.route", "/orders"), integer("big", 9007199254740993)}
+	child.Events = []*tracepb.Span_Event{{
+		Name:         "exception",
+		TimeUnixNano: uint64(start.Add(500 * time.Millisecond).UnixNano()),
+		Attributes:   []*commonpb.KeyValue{str("exception.type", "TimeoutError")},
+	}}
+	child.Links = []*tracepb.Span_Link{{
+		TraceId:    []byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+		SpanId:     []byte{8, 7, 6, 5, 4, 3, 2, 1},
+		Attributes: []*commonpb.KeyValue{str("link.kind", "follows")},
+	}}
+
+	sent := []*tracepb.ResourceSpans{
+		{
+			// a resource without attributes is still a resource
+			Resource: &resourcepb.Resource{},
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: "bare"},
+				Spans: []*tracepb.Span{root},
+			}},
+		},
+		{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{str("service.name", "api"), str("service.version", "1.0")},
+			},
+			SchemaUrl: "https://opentelemetry.io/schemas/1.4.0",
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{
+					Name:       "net/http",
+					Version:    "1.2.3",
+					Attributes: []*commonpb.KeyValue{str("library.language", "go")},
+				},
+				SchemaUrl: "https://opentelemetry.io/schemas/1.7.0",
+				Spans:     []*tracepb.Span{child},
+			}},
+		},
+	}
+
+	backend := storage.NewMemoryBackend()
+	server := newTestServer(t, storage.NewWriter(backend, "default"))
+
+	body, err := proto.Marshal(&collectortrace.ExportTraceServiceRequest{ResourceSpans: sent})
+	require.NoError(t, err)
+
+	res, err := http.Post(server.URL+"/v1/traces", "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	read, err := storage.NewReader(backend, "default").Trace(t.Context(), trace.TraceID(tid).String())
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+
+	for _, span := range read {
+		require.NotNil(t, span.Resource)
+	}
+
+	expected := &collectortrace.ExportTraceServiceRequest{ResourceSpans: sent}
+	actual := &collectortrace.ExportTraceServiceRequest{ResourceSpans: otlp.FromSpans(read)}
+	require.True(t, proto.Equal(expected, actual), "expected:\n%s\nactual:\n%s", prototext.Format(expected), prototext.Format(actual))
+}
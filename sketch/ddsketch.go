@@ -0,0 +1,157 @@
+package sketch
+
+import (
+	"math"
+	"slices"
+)
+
+const (
+	relativeAccuracy = 0.01
+	maxBuckets       = 2048
+)
+
+var (
+	gamma    = (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	logGamma = math.Log(gamma)
+)
+
+// DDSketch estimates quantiles with a relative error of 1%, by counting values in exponentially
+// sized buckets.  Memory is bounded by collapsing the smallest buckets together once there are
+// too many, which only affects the accuracy of the lowest quantiles.
+type DDSketch struct {
+	positive map[int]uint64
+	negative map[int]uint64
+	zeros    uint64
+
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func NewDDSketch() *DDSketch {
+	return &DDSketch{
+		positive: map[int]uint64{},
+		negative: map[int]uint64{},
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+func (s *DDSketch) Add(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+
+	switch {
+	case value > 0:
+		s.positive[bucket(value)]++
+		collapse(s.positive)
+	case value < 0:
+		s.negative[bucket(-value)]++
+		collapse(s.negative)
+	default:
+		s.zeros++
+	}
+
+	s.count++
+	s.sum += value
+	s.min = min(s.min, value)
+	s.max = max(s.max, value)
+}
+
+// Merge adds all the values seen by other to this sketch
+func (s *DDSketch) Merge(other *DDSketch) {
+	for index, n := range other.positive {
+		s.positive[index] += n
+	}
+	for index, n := range other.negative {
+		s.negative[index] += n
+	}
+	collapse(s.positive)
+	collapse(s.negative)
+
+	s.zeros += other.zeros
+	s.count += other.count
+	s.sum += other.sum
+	s.min = min(s.min, other.min)
+	s.max = max(s.max, other.max)
+}
+
+func (s *DDSketch) Count() uint64 { return s.count }
+func (s *DDSketch) Sum() float64  { return s.sum }
+func (s *DDSketch) Min() float64  { return s.min }
+func (s *DDSketch) Max() float64  { return s.max }
+
+// Quantile estimates the value at q, between 0 and 1.  It is NaN when the sketch is empty.
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	seen := uint64(0)
+
+	// negative values are stored by magnitude, so the largest bucket is the smallest value
+	negatives := sortedBuckets(s.negative)
+	slices.Reverse(negatives)
+	for _, index := range negatives {
+		seen += s.negative[index]
+		if seen > rank {
+			return s.clamp(-value(index))
+		}
+	}
+
+	seen += s.zeros
+	if seen > rank {
+		return 0
+	}
+
+	for _, index := range sortedBuckets(s.positive) {
+		seen += s.positive[index]
+		if seen > rank {
+			return s.clamp(value(index))
+		}
+	}
+
+	return s.max
+}
+
+// clamp keeps estimates inside the range of values actually seen
+func (s *DDSketch) clamp(v float64) float64 {
+	return max(s.min, min(s.max, v))
+}
+
+func bucket(v float64) int {
+	return int(math.Ceil(math.Log(v) / logGamma))
+}
+
+// value is the midpoint of a bucket, which is within the relative accuracy of everything in it
+func value(index int) float64 {
+	return 2 * math.Pow(gamma, float64(index)) / (gamma + 1)
+}
+
+func sortedBuckets(buckets map[int]uint64) []int {
+	indexes := make([]int, 0, len(buckets))
+	for index := range buckets {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	return indexes
+}
+
+// collapse merges the lowest buckets into one once there are more than maxBuckets
+func collapse(buckets map[int]uint64) {
+	if len(buckets) <= maxBuckets {
+		return
+	}
+
+	indexes := sortedBuckets(buckets)
+	excess := indexes[:len(indexes)-maxBuckets+1]
+	into := excess[len(excess)-1]
+
+	for _, index := range excess[:len(excess)-1] {
+		buckets[into] += buckets[index]
+		delete(buckets, index)
+	}
+}
@@ -0,0 +1,76 @@
+package sketch
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDDSketchQuantiles(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	values := make([]float64, 10_000)
+	for i := range values {
+		values[i] = r.ExpFloat64() * 100
+	}
+
+	sketch := NewDDSketch()
+	for _, v := range values {
+		sketch.Add(v)
+	}
+
+	slices.Sort(values)
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		expected := values[int(q*float64(len(values)-1))]
+		require.InEpsilon(t, expected, sketch.Quantile(q), 0.01, "quantile %v", q)
+	}
+
+	require.Equal(t, uint64(len(values)), sketch.Count())
+	require.Equal(t, values[0], sketch.Min())
+	require.Equal(t, values[len(values)-1], sketch.Max())
+}
+
+func TestDDSketchSigns(t *testing.T) {
+	sketch := NewDDSketch()
+	for _, v := range []float64{-10, -1, 0, 0, 1, 10} {
+		sketch.Add(v)
+	}
+
+	require.Equal(t, -10.0, sketch.Quantile(0))
+	require.InEpsilon(t, -1, sketch.Quantile(0.2), 0.01)
+	require.Equal(t, 0.0, sketch.Quantile(0.5))
+	require.InEpsilon(t, 1, sketch.Quantile(0.8), 0.01)
+	require.Equal(t, 10.0, sketch.Quantile(1))
+	require.Equal(t, 0.0, sketch.Sum())
+}
+
+func TestDDSketchEmpty(t *testing.T) {
+	require.True(t, math.IsNaN(NewDDSketch().Quantile(0.5)))
+}
+
+func TestDDSketchBounded(t *testing.T) {
+	sketch := NewDDSketch()
+	for i := range 100_000 {
+		sketch.Add(math.Pow(1.001, float64(i)))
+	}
+
+	require.LessOrEqual(t, len(sketch.positive), maxBuckets)
+	require.InEpsilon(t, math.Pow(1.001, 98_999), sketch.Quantile(0.99), 0.01)
+}
+
+func TestDDSketchMerge(t *testing.T) {
+	a := NewDDSketch()
+	b := NewDDSketch()
+	for i := 1; i <= 100; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 100))
+	}
+
+	a.Merge(b)
+	require.Equal(t, uint64(200), a.Count())
+	require.InEpsilon(t, 100, a.Quantile(0.5), 0.01)
+	require.Equal(t, 1.0, a.Min())
+	require.Equal(t, 200.0, a.Max())
+}
@@ -0,0 +1,77 @@
+package sketch
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	cases := []int{0, 1, 10, 1000, 100_000}
+
+	for _, n := range cases {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			hll := NewHyperLogLog()
+			for i := range n {
+				hll.Add(fmt.Sprint(i))
+				hll.Add(fmt.Sprint(i))
+			}
+
+			require.InEpsilon(t, float64(n)+1, float64(hll.Count())+1, 0.02)
+		})
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	a := NewHyperLogLog()
+	b := NewHyperLogLog()
+
+	for i := range 5000 {
+		a.Add(fmt.Sprint(i))
+		b.Add(fmt.Sprint(i + 2500))
+	}
+
+	a.Merge(b)
+	require.InEpsilon(t, 7500, float64(a.Count()), 0.02)
+}
+
+func TestHyperLogLogSparse(t *testing.T) {
+	small := NewHyperLogLog()
+	for i := range 100 {
+		small.Add(fmt.Sprint(i))
+	}
+	require.Nil(t, small.registers)
+	require.LessOrEqual(t, len(small.sparse), 100)
+
+	large := NewHyperLogLog()
+	for i := range 10_000 {
+		large.Add(fmt.Sprint(i))
+	}
+	require.NotNil(t, large.registers)
+	require.Nil(t, large.sparse)
+
+	// every combination of sparse and dense merges to the same registers
+	dense := func(h *HyperLogLog) []uint8 {
+		c := NewHyperLogLog()
+		c.Merge(h)
+		c.densify()
+		return c.registers
+	}
+
+	sparseIntoDense := NewHyperLogLog()
+	sparseIntoDense.Merge(large)
+	sparseIntoDense.Merge(small)
+
+	denseIntoSparse := NewHyperLogLog()
+	denseIntoSparse.Merge(small)
+	denseIntoSparse.Merge(large)
+
+	require.Equal(t, dense(sparseIntoDense), dense(denseIntoSparse))
+	require.InEpsilon(t, 10_000, float64(denseIntoSparse.Count()), 0.02)
+
+	// a sparse sketch counts exactly as it would once dense
+	count := small.Count()
+	small.densify()
+	require.Equal(t, count, small.Count())
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+// ErrInvalidKey is returned for keys a backend can't store, such as ones escaping its root
+var ErrInvalidKey = errors.New("invalid key")
+
+// listPageSize matches the maximum number of keys S3 returns from a single ListObjectsV2 call
+const listPageSize = 1000
+
+// Backend is the object store the span layout in paths.go is written to.  Keys are
+// always `/` separated, regardless of how the backend stores them.
+type Backend interface {
+	Put(ctx context.Context, key string, content []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string, continuation string) (*ListPage, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ListPage is a single page of keys, in lexicographic order.  If there are more keys
+// under the prefix, Continuation is set and can be passed to the next List call.
+type ListPage struct {
+	Keys         []string
+	Continuation string
+}
+
+// listPages yields every page of keys under the prefix, following continuation tokens
+// until the backend reports there are no more.  Stopping the iteration early means no
+// further pages are requested.
+func listPages(ctx context.Context, backend Backend, prefix string) iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		continuation := ""
+
+		for {
+			page, err := backend.List(ctx, prefix, continuation)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(page.Keys, nil) {
+				return
+			}
+
+			if page.Continuation == "" {
+				return
+			}
+			continuation = page.Continuation
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var _ Backend = &FileSystemBackend{}
+
+// temporary files are written next to their destination so the rename is atomic, and are
+// never returned from List.
+const tempFilePrefix = ".romulus-tmp-"
+
+type FileSystemBackend struct {
+	root string
+}
+
+func NewFileSystemBackend(root string) *FileSystemBackend {
+	return &FileSystemBackend{
+		root: root,
+	}
+}
+
+func (b *FileSystemBackend) Put(ctx context.Context, key string, content []byte) error {
+	dest, err := b.filePath(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(dest)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (b *FileSystemBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := b.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// List walks the directory containing the prefix in the same order as S3, which sorts keys as
+// whole strings rather than per directory.  Directories which sort before the continuation are
+// skipped, and the walk stops once the page is full, so each page only reads what it returns.
+func (b *FileSystemBackend) List(ctx context.Context, prefix string, continuation string) (*ListPage, error) {
+	base := prefix[:strings.LastIndex(prefix, "/")+1]
+
+	dir := b.root
+	if base != "" {
+		var err error
+		if dir, err = b.filePath(base); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := []string{}
+	visit := func(key string) bool {
+		if strings.HasPrefix(key, prefix) && key > continuation {
+			keys = append(keys, key)
+		}
+		return len(keys) <= listPageSize
+	}
+
+	if _, err := b.walkSorted(ctx, dir, base, prefix, continuation, visit); err != nil {
+		return nil, err
+	}
+
+	page := &ListPage{Keys: keys}
+	if len(keys) > listPageSize {
+		page.Keys = keys[:listPageSize]
+		page.Continuation = page.Keys[listPageSize-1]
+	}
+
+	return page, nil
+}
+
+// walkSorted visits the files under dir in key order, returning false once visit asks to stop.
+// A directory sorts as its name followed by `/`, as that is how every key inside it starts.
+func (b *FileSystemBackend) walkSorted(ctx context.Context, dir, dirKey, prefix, continuation string, visit func(key string) bool) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	sortKey := func(e fs.DirEntry) string {
+		if e.IsDir() {
+			return e.Name() + "/"
+		}
+		return e.Name()
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(sortKey(a), sortKey(b))
+	})
+
+	for _, entry := range entries {
+		key := dirKey + sortKey(entry)
+
+		if !entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), tempFilePrefix) {
+				continue
+			}
+			if !visit(key) {
+				return false, nil
+			}
+			continue
+		}
+
+		// skip directories outside the prefix, and those whose keys all sort before the
+		// continuation
+		if !strings.HasPrefix(key, prefix) && !strings.HasPrefix(prefix, key) {
+			continue
+		}
+		if key < continuation && !strings.HasPrefix(continuation, key) {
+			continue
+		}
+
+		more, err := b.walkSorted(ctx, filepath.Join(dir, entry.Name()), key, prefix, continuation, visit)
+		if err != nil || !more {
+			return more, err
+		}
+	}
+
+	return true, nil
+}
+
+func (b *FileSystemBackend) Delete(ctx context.Context, key string) error {
+	p, err := b.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// tidy up any directories left empty, stopping at the first one which still has content
+	root := filepath.Clean(b.root)
+	for dir := filepath.Dir(p); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// filePath maps a key to its file under the root.  Keys which would resolve outside the root,
+// such as `../x` or an absolute path, are refused.
+func (b *FileSystemBackend) filePath(key string) (string, error) {
+	p := filepath.FromSlash(key)
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(b.root, p), nil
+}
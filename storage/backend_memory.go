@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var _ Backend = &MemoryBackend{}
+
+// MemoryBackend keeps everything in process, listing keys the same way S3 does: sorted,
+// filtered by prefix, and split into pages of 1000 keys.
+type MemoryBackend struct {
+	mu    sync.RWMutex
+	store map[string][]byte
+	keys  []string
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		store: map[string][]byte{},
+	}
+}
+
+func (b *MemoryBackend) Put(ctx context.Context, key string, content []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, found := b.store[key]; !found {
+		i, _ := slices.BinarySearch(b.keys, key)
+		b.keys = slices.Insert(b.keys, i, key)
+	}
+
+	b.store[key] = bytes.Clone(content)
+	return nil
+}
+
+func (b *MemoryBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	content, found := b.store[key]
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (b *MemoryBackend) List(ctx context.Context, prefix string, continuation string) (*ListPage, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	start, _ := slices.BinarySearch(b.keys, max(prefix, continuation))
+	if start < len(b.keys) && b.keys[start] == continuation {
+		start++
+	}
+
+	page := &ListPage{Keys: []string{}}
+	for i := start; i < len(b.keys) && strings.HasPrefix(b.keys[i], prefix); i++ {
+		if len(page.Keys) == listPageSize {
+			page.Continuation = page.Keys[listPageSize-1]
+			break
+		}
+		page.Keys = append(page.Keys, b.keys[i])
+	}
+
+	return page, nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, found := b.store[key]; !found {
+		return nil
+	}
+
+	delete(b.store, key)
+	if i, found := slices.BinarySearch(b.keys, key); found {
+		b.keys = slices.Delete(b.keys, i, i+1)
+	}
+
+	return nil
+}
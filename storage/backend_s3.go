@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var _ Backend = &S3Backend{}
+
+type S3Backend struct {
+	s3        *s3.Client
+	bucket    string
+	keyPrefix string
+}
+
+type S3Options struct {
+	Bucket    string
+	KeyPrefix string
+	Endpoint  string
+	PathStyle bool
+}
+
+// NewS3Backend creates a client from the default aws config chain, so credentials and region
+// come from the environment.  All keys are stored under the KeyPrefix, which lets several
+// teams share a single bucket.
+func NewS3Backend(ctx context.Context, opts S3Options) (*S3Backend, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("an s3 bucket name is required")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+		o.UsePathStyle = opts.PathStyle
+	})
+
+	prefix := strings.Trim(opts.KeyPrefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &S3Backend{
+		s3:        client,
+		bucket:    opts.Bucket,
+		keyPrefix: prefix,
+	}, nil
+}
+
+func (b *S3Backend) Put(ctx context.Context, key string, content []byte) error {
+	_, err := b.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.keyPrefix + key),
+		Body:   bytes.NewReader(content),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := b.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.keyPrefix + key),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return obj.Body, nil
+}
+
+func (b *S3Backend) List(ctx context.Context, prefix string, continuation string) (*ListPage, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(b.keyPrefix + prefix),
+	}
+	if continuation != "" {
+		input.ContinuationToken = aws.String(continuation)
+	}
+
+	ls, err := b.s3.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ListPage{
+		Keys: make([]string, len(ls.Contents)),
+	}
+	for i, item := range ls.Contents {
+		page.Keys[i] = strings.TrimPrefix(*item.Key, b.keyPrefix)
+	}
+
+	if aws.ToBool(ls.IsTruncated) {
+		page.Continuation = aws.ToString(ls.NextContinuationToken)
+	}
+
+	return page, nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.keyPrefix + key),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestFileSystemBackend(t *testing.T) {
+	root := t.TempDir()
+	backend := NewFileSystemBackend(root)
+
+	testBackend(t, backend)
+
+	t.Run("no temporary files are left behind", func(t *testing.T) {
+		err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+			require.NoError(t, err)
+			require.NotContains(t, d.Name(), tempFilePrefix)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestFileSystemBackendTraversal(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "root")
+	backend := NewFileSystemBackend(root)
+
+	for _, key := range []string{"../escaped", "a/../../escaped", "/etc/escaped", ""} {
+		t.Run(key, func(t *testing.T) {
+			require.ErrorIs(t, backend.Put(t.Context(), key, []byte("content")), ErrInvalidKey)
+
+			_, err := backend.Get(t.Context(), key)
+			require.ErrorIs(t, err, ErrInvalidKey)
+
+			require.ErrorIs(t, backend.Delete(t.Context(), key), ErrInvalidKey)
+		})
+	}
+
+	_, err := backend.List(t.Context(), "../", "")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	t.Run("attribute keys stay in their namespace", func(t *testing.T) {
+		spans := createTraces([]attribute.KeyValue{
+			attribute.String("../../../../escaped", "value"),
+			attribute.String("../resource/service.name", "value"),
+			attribute.String("..", "value"),
+		})
+		require.NoError(t, NewWriter(backend, "default").Write(t.Context(), spans))
+
+		entries, err := os.ReadDir(parent)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		reader := NewReader(backend, "default")
+		for _, key := range []string{"../../../../escaped", "../resource/service.name", ".."} {
+			matched, err := reader.matchPredicate(t.Context(), allSpans(spans), Is(attribute.String(key, "value")).In(SpanScope))
+			require.NoError(t, err)
+			require.Len(t, matched, 1, key)
+		}
+
+		matched, err := reader.matchPredicate(t.Context(), allSpans(spans), Has("service.name").In(ResourceScope))
+		require.NoError(t, err)
+		require.Len(t, matched, 1)
+	})
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, NewMemoryBackend())
+}
+
+func testBackend(t *testing.T, backend Backend) {
+
+	t.Run("put and get", func(t *testing.T) {
+		require.NoError(t, backend.Put(t.Context(), "get/one", []byte("the content")))
+
+		body, err := backend.Get(t.Context(), "get/one")
+		require.NoError(t, err)
+		defer body.Close()
+
+		content, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, "the content", string(content))
+	})
+
+	t.Run("put overwrites", func(t *testing.T) {
+		require.NoError(t, backend.Put(t.Context(), "overwrite/one", []byte("first")))
+		require.NoError(t, backend.Put(t.Context(), "overwrite/one", []byte("second")))
+
+		body, err := backend.Get(t.Context(), "overwrite/one")
+		require.NoError(t, err)
+		defer body.Close()
+
+		content, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, "second", string(content))
+	})
+
+	t.Run("get missing key", func(t *testing.T) {
+		_, err := backend.Get(t.Context(), "missing/one")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("list is lexicographic by whole key", func(t *testing.T) {
+		keys := []string{
+			"order/a/2",
+			"order/a.b/1",
+			"order/a/1",
+			"order/a-c/1",
+			"order/ab/1",
+		}
+		for _, key := range keys {
+			require.NoError(t, backend.Put(t.Context(), key, empty))
+		}
+
+		page, err := backend.List(t.Context(), "order/", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"order/a-c/1",
+			"order/a.b/1",
+			"order/a/1",
+			"order/a/2",
+			"order/ab/1",
+		}, page.Keys)
+		require.Empty(t, page.Continuation)
+	})
+
+	t.Run("list filters by partial prefix", func(t *testing.T) {
+		for _, key := range []string{"partial/1729000001/a", "partial/1729000002/b", "partial/1739000000/c"} {
+			require.NoError(t, backend.Put(t.Context(), key, empty))
+		}
+
+		page, err := backend.List(t.Context(), "partial/17290", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"partial/1729000001/a", "partial/1729000002/b"}, page.Keys)
+	})
+
+	t.Run("list missing prefix", func(t *testing.T) {
+		page, err := backend.List(t.Context(), "nothing/here/", "")
+		require.NoError(t, err)
+		require.Empty(t, page.Keys)
+		require.Empty(t, page.Continuation)
+	})
+
+	t.Run("list pages", func(t *testing.T) {
+		total := listPageSize + listPageSize/2
+		for i := range total {
+			require.NoError(t, backend.Put(t.Context(), fmt.Sprintf("paged/%05d", i), empty))
+		}
+
+		first, err := backend.List(t.Context(), "paged/", "")
+		require.NoError(t, err)
+		require.Len(t, first.Keys, listPageSize)
+		require.NotEmpty(t, first.Continuation)
+		require.Equal(t, "paged/00000", first.Keys[0])
+
+		second, err := backend.List(t.Context(), "paged/", first.Continuation)
+		require.NoError(t, err)
+		require.Len(t, second.Keys, total-listPageSize)
+		require.Empty(t, second.Continuation)
+		require.Equal(t, fmt.Sprintf("paged/%05d", listPageSize), second.Keys[0])
+	})
+
+	t.Run("list pages across directories", func(t *testing.T) {
+		expected := []string{}
+		for _, dir := range []string{"a", "a.b", "ab"} {
+			for i := range listPageSize / 2 {
+				key := fmt.Sprintf("nested/%s/%04d", dir, i)
+				require.NoError(t, backend.Put(t.Context(), key, empty))
+				expected = append(expected, key)
+			}
+		}
+		slices.Sort(expected)
+
+		keys := []string{}
+		continuation := ""
+		for {
+			page, err := backend.List(t.Context(), "nested/", continuation)
+			require.NoError(t, err)
+			keys = append(keys, page.Keys...)
+
+			if page.Continuation == "" {
+				break
+			}
+			continuation = page.Continuation
+		}
+
+		require.Equal(t, expected, keys)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, backend.Put(t.Context(), "delete/one", empty))
+		require.NoError(t, backend.Put(t.Context(), "delete/two", empty))
+		require.NoError(t, backend.Delete(t.Context(), "delete/one"))
+
+		_, err := backend.Get(t.Context(), "delete/one")
+		require.ErrorIs(t, err, ErrNotFound)
+
+		page, err := backend.List(t.Context(), "delete/", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"delete/two"}, page.Keys)
+
+		require.NoError(t, backend.Delete(t.Context(), "delete/missing"))
+	})
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"path"
+	"romulus/domain"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DurationField is the span's duration in milliseconds.  Unlike the other intrinsics it has its
+// own index of buckets, so filters on it can skip the buckets which can't match.
+const DurationField = domain.DurationField
+
+// durationBuckets covers every possible bucket, as a bucket is the bit length of the duration
+const durationBuckets = 65
+
+// durationBucket is 0 for spans under 1ms, otherwise bucket b holds [2^(b-1), 2^b) ms
+func durationBucket(d time.Duration) int {
+	return bits.Len64(uint64(max(d/time.Millisecond, 0)))
+}
+
+// bucketBounds is the range of milliseconds a bucket holds, including lo but not hi
+func bucketBounds(bucket int) (float64, float64) {
+	if bucket == 0 {
+		return math.Inf(-1), 1
+	}
+	return math.Exp2(float64(bucket - 1)), math.Exp2(float64(bucket))
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// matchDuration answers a predicate on DurationField from the duration index.  Buckets entirely
+// inside the predicate match without reading anything, and only the buckets which straddle the
+// predicate's value need each span's exact duration reading.
+func (s *Reader) matchDuration(ctx context.Context, spans map[string]bool, predicate Predicate) (map[string]bool, error) {
+	switch predicate.Op {
+	case Exists:
+		return spans, nil
+	case NotExists:
+		return map[string]bool{}, nil
+	case Equal, NotEqual, Less, LessOrEqual, Greater, GreaterOrEqual:
+		if !isNumeric(predicate.Value) || predicate.Length {
+			return nil, fmt.Errorf("%s: %s can only be compared to a number", predicate, DurationField)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %s can only be compared to a number", predicate, DurationField)
+	}
+
+	sids := map[string]bool{}
+	for bucket := range durationBuckets {
+		all, some := predicate.bucketMatches(bucketBounds(bucket))
+		if !some {
+			continue
+		}
+
+		for keys, err := range listPages(ctx, s.backend, durationPrefix(s.dataset, bucket)) {
+			if err != nil {
+				return nil, err
+			}
+
+			for _, key := range keys {
+				sid := path.Base(key)
+				if !spans[sid] {
+					continue
+				}
+
+				if all {
+					sids[sid] = true
+					continue
+				}
+
+				d, err := s.readDuration(ctx, key)
+				if err != nil {
+					return nil, err
+				}
+
+				if predicate.matches(attribute.Float64Value(durationMs(d))) {
+					sids[sid] = true
+				}
+			}
+		}
+	}
+
+	return sids, nil
+}
+
+// bucketMatches checks whether all, or only some, of the values in [lo, hi) can match
+func (p Predicate) bucketMatches(lo, hi float64) (all bool, some bool) {
+	v := asFloat(p.Value)
+
+	switch p.Op {
+	case Less:
+		return hi <= v, lo < v
+	case LessOrEqual:
+		return hi <= v, lo <= v
+	case Greater:
+		return lo > v, hi > v
+	case GreaterOrEqual:
+		return lo >= v, hi > v
+	case Equal:
+		return false, lo <= v && v < hi
+	case NotEqual:
+		return v < lo || v >= hi, true
+	}
+
+	return false, false
+}
+
+func (s *Reader) readDuration(ctx context.Context, key string) (time.Duration, error) {
+	body, err := s.backend.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	var d time.Duration
+	if err := json.NewDecoder(body).Decode(&d); err != nil {
+		return 0, err
+	}
+
+	return d, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"romulus/domain"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDurationBucket(t *testing.T) {
+	cases := []struct {
+		Duration time.Duration
+		Bucket   int
+	}{
+		{0, 0},
+		{-time.Second, 0},
+		{999 * time.Microsecond, 0},
+		{time.Millisecond, 1},
+		{1999 * time.Microsecond, 1},
+		{2 * time.Millisecond, 2},
+		{1500 * time.Millisecond, 11},
+		{2048 * time.Millisecond, 12},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Duration.String(), func(t *testing.T) {
+			bucket := durationBucket(tc.Duration)
+			require.Equal(t, tc.Bucket, bucket)
+
+			lo, hi := bucketBounds(bucket)
+			ms := durationMs(tc.Duration)
+			if ms >= 0 {
+				require.True(t, lo <= ms && ms < hi, "%v should be in [%v, %v)", ms, lo, hi)
+			}
+		})
+	}
+}
+
+func TestFilterDuration(t *testing.T) {
+	durations := []time.Duration{
+		500 * time.Microsecond,
+		5 * time.Millisecond,
+		1500 * time.Millisecond,
+		3 * time.Second,
+	}
+	spans := createDurationSpans(durations...)
+
+	backend := &countingBackend{Backend: createTestBackend(t)}
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	timeRange := Range{Start: spans[0].StartTime, Finish: spans[len(spans)-1].EndTime}
+
+	cases := []struct {
+		Name      string
+		Predicate Predicate
+		Expected  int
+	}{
+		{"greater", Compare(DurationField, Greater, attribute.IntValue(2000)).In(IntrinsicScope), 1},
+		{"greater or equal float", Compare(DurationField, GreaterOrEqual, attribute.Float64Value(1500)).In(IntrinsicScope), 2},
+		{"less", Compare(DurationField, Less, attribute.IntValue(1)).In(IntrinsicScope), 1},
+		{"less or equal", Compare(DurationField, LessOrEqual, attribute.IntValue(5)).In(IntrinsicScope), 2},
+		{"equal", Compare(DurationField, Equal, attribute.IntValue(3000)).In(IntrinsicScope), 1},
+		{"not equal", Compare(DurationField, NotEqual, attribute.IntValue(3000)).In(IntrinsicScope), 3},
+		{"exists", Has(DurationField).In(IntrinsicScope), 4},
+		{"not exists", Lacks(DurationField).In(IntrinsicScope), 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			traceIds, err := reader.Filter(t.Context(), timeRange, SpanFilter{tc.Predicate})
+			require.NoError(t, err)
+			require.Len(t, traceIds, tc.Expected)
+		})
+	}
+
+	t.Run("only straddling buckets are read", func(t *testing.T) {
+		backend.gets.Store(0)
+
+		// 1.5s straddles the 1024-2048ms bucket so is read, 3s is in a bucket which entirely
+		// matches, and the rest are in buckets which can't match
+		matched, err := reader.matchPredicate(t.Context(), allSpans(spans), Compare(DurationField, Greater, attribute.IntValue(2000)).In(IntrinsicScope))
+		require.NoError(t, err)
+		require.Len(t, matched, 1)
+		require.EqualValues(t, 1, backend.gets.Load())
+	})
+
+	t.Run("strings are rejected", func(t *testing.T) {
+		_, err := reader.Filter(t.Context(), timeRange, SpanFilter{Compare(DurationField, Equal, attribute.StringValue("2s")).In(IntrinsicScope)})
+		require.Error(t, err)
+	})
+}
+
+func createDurationSpans(durations ...time.Duration) []domain.Span {
+	start := time.Now()
+	tp, exporter := createTraceProvider()
+	tr := tp.Tracer("tests")
+
+	for i, d := range durations {
+		ts := start.Add(time.Duration(i) * time.Second)
+		_, span := tr.Start(context.Background(), "span", trace.WithNewRoot(), trace.WithTimestamp(ts))
+		span.End(trace.WithTimestamp(ts.Add(d)))
+	}
+
+	return exporter.GetSpans()
+}
+
+func allSpans(spans []domain.Span) map[string]bool {
+	sids := map[string]bool{}
+	for _, span := range spans {
+		sids[span.SpanContext.SpanID().String()] = true
+	}
+	return sids
+}
+
+// countingBackend counts how many keys are read, and how many pages are listed.  Busiest is the
+// most reads which were in flight at once.
+type countingBackend struct {
+	Backend
+	gets    atomic.Int64
+	lists   atomic.Int64
+	reading atomic.Int64
+	busiest atomic.Int64
+}
+
+func (b *countingBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b.gets.Add(1)
+
+	reading := b.reading.Add(1)
+	defer b.reading.Add(-1)
+	for busiest := b.busiest.Load(); reading > busiest && !b.busiest.CompareAndSwap(busiest, reading); busiest = b.busiest.Load() {
+	}
+
+	// give the other reads a chance to overlap
+	time.Sleep(time.Millisecond)
+	return b.Backend.Get(ctx, key)
+}
+
+func (b *countingBackend) List(ctx context.Context, prefix string, continuation string) (*ListPage, error) {
+	b.lists.Add(1)
+	return b.Backend.List(ctx, prefix, continuation)
+}
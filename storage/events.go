@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"romulus/domain"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// correlateEvents keeps the spans which have a single event satisfying every event predicate.
+// The index only records which spans have an event matching each predicate, not which event, so
+// `event.name = "exception" and event.exception.type = "TimeoutError"` would otherwise match
+// a span where the two hold for different events.  The spans left after the index has narrowed
+// them down are read to check their events directly.
+func (s *Reader) correlateEvents(ctx context.Context, spans map[string]bool, predicates []Predicate) (map[string]bool, error) {
+	tests := []func(domain.Event) bool{}
+	for _, predicate := range predicates {
+		if predicate.Scope != EventScope && predicate.Scope != EventIntrinsicScope {
+			continue
+		}
+
+		// not exists is about every event, so there is nothing to correlate
+		if predicate.Op == NotExists {
+			continue
+		}
+
+		test, err := predicate.eventTest()
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	}
+
+	if len(tests) < 2 || len(spans) == 0 {
+		return spans, nil
+	}
+
+	matchesAll := func(event domain.Event) bool {
+		for _, test := range tests {
+			if !test(event) {
+				return false
+			}
+		}
+		return true
+	}
+
+	matched := map[string]bool{}
+	for batch := range slices.Chunk(slices.Sorted(maps.Keys(spans)), spanBatchSize) {
+		read, err := s.readSpans(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, span := range read {
+			if slices.ContainsFunc(span.Events, matchesAll) {
+				matched[batch[i]] = true
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// eventTest checks an event scoped predicate against a single event, the same way it is
+// checked against the index
+func (p Predicate) eventTest() (func(domain.Event) bool, error) {
+	matches, err := p.matcher()
+	if err != nil {
+		return nil, err
+	}
+	types := p.types()
+
+	value := func(event domain.Event) (attribute.Value, bool) {
+		if p.Scope == EventIntrinsicScope {
+			return attribute.StringValue(event.Name), string(p.Key) == eventNameKey
+		}
+
+		for _, attr := range event.Attributes {
+			if attr.Key == p.Key {
+				return attr.Value, true
+			}
+		}
+		return attribute.Value{}, false
+	}
+
+	return func(event domain.Event) bool {
+		v, found := value(event)
+		if !found {
+			return false
+		}
+		if p.Op == Exists {
+			return true
+		}
+		return slices.Contains(types, v.Type()) && matches(v)
+	}, nil
+}
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
+)
+
+// SpanExpr is a condition which a single span has to satisfy.  Predicate and SpanFilter are
+// the leaves, and SpanAnd, SpanOr and SpanNot combine them.
+type SpanExpr interface {
+	matchSpans(ctx context.Context, e *evaluation, spans map[string]bool) (map[string]bool, error)
+}
+
+// TraceExpr is a condition on a whole trace.  A SpanFilter matches a trace when any one of the
+// trace's spans match it, or use AnySpan for other span expressions.  TraceAnd, TraceOr and
+// TraceNot combine them, so each part can be satisfied by a different span.
+type TraceExpr interface {
+	matchTraces(ctx context.Context, e *evaluation) (map[trace.TraceID]bool, error)
+}
+
+// SpanAnd matches spans which match all of the expressions
+type SpanAnd []SpanExpr
+
+// SpanOr matches spans which match any of the expressions
+type SpanOr []SpanExpr
+
+// SpanNot matches spans which don't match the expression.  Unlike NotEqual, this includes
+// spans which don't have the attribute at all.
+type SpanNot struct {
+	Expr SpanExpr
+}
+
+// AnySpan matches traces which contain at least one span matching the expression
+type AnySpan struct {
+	Expr SpanExpr
+}
+
+// TraceAnd matches traces which match all of the expressions
+type TraceAnd []TraceExpr
+
+// TraceOr matches traces which match any of the expressions
+type TraceOr []TraceExpr
+
+// TraceNot matches traces which have spans in the time range, but don't match the expression
+type TraceNot struct {
+	Expr TraceExpr
+}
+
+var (
+	_ SpanExpr  = Predicate{}
+	_ SpanExpr  = SpanFilter{}
+	_ SpanExpr  = SpanAnd{}
+	_ SpanExpr  = SpanOr{}
+	_ SpanExpr  = SpanNot{}
+	_ TraceExpr = SpanFilter{}
+	_ TraceExpr = AnySpan{}
+	_ TraceExpr = TraceAnd{}
+	_ TraceExpr = TraceOr{}
+	_ TraceExpr = TraceNot{}
+)
+
+func (p Predicate) matchSpans(ctx context.Context, e *evaluation, spans map[string]bool) (map[string]bool, error) {
+	return e.reader.matchPredicate(ctx, spans, p)
+}
+
+// each predicate only needs to check the spans the previous ones matched
+func (f SpanFilter) matchSpans(ctx context.Context, e *evaluation, spans map[string]bool) (map[string]bool, error) {
+	for _, predicate := range f {
+		matched, err := predicate.matchSpans(ctx, e, spans)
+		if err != nil {
+			return nil, err
+		}
+		spans = matched
+	}
+	return e.reader.correlateEvents(ctx, spans, f)
+}
+
+func (f SpanFilter) matchTraces(ctx context.Context, e *evaluation) (map[trace.TraceID]bool, error) {
+	return AnySpan{Expr: f}.matchTraces(ctx, e)
+}
+
+func (a SpanAnd) matchSpans(ctx context.Context, e *evaluation, spans map[string]bool) (map[string]bool, error) {
+	predicates := []Predicate{}
+	for _, expr := range a {
+		matched, err := expr.matchSpans(ctx, e, spans)
+		if err != nil {
+			return nil, err
+		}
+		spans = matched
+
+		switch p := expr.(type) {
+		case Predicate:
+			predicates = append(predicates, p)
+		case SpanFilter:
+			predicates = append(predicates, p...)
+		}
+	}
+	return e.reader.correlateEvents(ctx, spans, predicates)
+}
+
+func (o SpanOr) matchSpans(ctx context.Context, e *evaluation, spans map[string]bool) (map[string]bool, error) {
+	result := map[string]bool{}
+	for _, expr := range o {
+		matched, err := expr.matchSpans(ctx, e, spans)
+		if err != nil {
+			return nil, err
+		}
+		union(result, matched)
+	}
+	return result, nil
+}
+
+func (n SpanNot) matchSpans(ctx context.Context, e *evaluation, spans map[string]bool) (map[string]bool, error) {
+	matched, err := n.Expr.matchSpans(ctx, e, spans)
+	if err != nil {
+		return nil, err
+	}
+	return difference(spans, matched), nil
+}
+
+func (a AnySpan) matchTraces(ctx context.Context, e *evaluation) (map[trace.TraceID]bool, error) {
+	matched, err := a.Expr.matchSpans(ctx, e, e.spans)
+	if err != nil {
+		return nil, err
+	}
+	return e.traceIds(ctx, matched)
+}
+
+func (a TraceAnd) matchTraces(ctx context.Context, e *evaluation) (map[trace.TraceID]bool, error) {
+	var result map[trace.TraceID]bool
+	for i, expr := range a {
+		matched, err := expr.matchTraces(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			result = matched
+		} else {
+			result = intersection(result, matched)
+		}
+
+		if len(result) == 0 {
+			break
+		}
+	}
+
+	if result == nil {
+		return e.allTraces(ctx)
+	}
+	return result, nil
+}
+
+func (o TraceOr) matchTraces(ctx context.Context, e *evaluation) (map[trace.TraceID]bool, error) {
+	result := map[trace.TraceID]bool{}
+	for _, expr := range o {
+		matched, err := expr.matchTraces(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		union(result, matched)
+	}
+	return result, nil
+}
+
+func (n TraceNot) matchTraces(ctx context.Context, e *evaluation) (map[trace.TraceID]bool, error) {
+	matched, err := n.Expr.matchTraces(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := e.allTraces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return difference(all, matched), nil
+}
+
+// evaluation holds the state of a single Filter call: the spans in the time range, and the
+// trace each span belongs to, which is only known once the span has been read.
+type evaluation struct {
+	reader *Reader
+	spans  map[string]bool
+
+	mu     sync.Mutex
+	traces map[string]trace.TraceID
+}
+
+func newEvaluation(reader *Reader, spans map[string]bool) *evaluation {
+	return &evaluation{
+		reader: reader,
+		spans:  spans,
+		traces: make(map[string]trace.TraceID, len(spans)),
+	}
+}
+
+// traceIds finds the traces the spans belong to, reading any spans not already seen
+func (e *evaluation) traceIds(ctx context.Context, spans map[string]bool) (map[trace.TraceID]bool, error) {
+	wg := errgroup.Group{}
+	wg.SetLimit(concurrentReads)
+	for sid := range spans {
+		e.mu.Lock()
+		_, found := e.traces[sid]
+		e.mu.Unlock()
+
+		if found {
+			continue
+		}
+
+		wg.Go(func() error {
+			span, err := e.reader.readSpanContents(ctx, sid)
+			if err != nil {
+				return err
+			}
+
+			e.mu.Lock()
+			e.traces[sid] = span.SpanContext.TraceID()
+			e.mu.Unlock()
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	tids := map[trace.TraceID]bool{}
+	for sid := range spans {
+		tids[e.traces[sid]] = true
+	}
+	return tids, nil
+}
+
+// allTraces is every trace with a span in the time range
+func (e *evaluation) allTraces(ctx context.Context) (map[trace.TraceID]bool, error) {
+	return e.traceIds(ctx, e.spans)
+}
+
+func union[K comparable](into, other map[K]bool) {
+	for k := range other {
+		into[k] = true
+	}
+}
+
+func intersection[K comparable](a, b map[K]bool) map[K]bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+
+	result := make(map[K]bool, len(a))
+	for k := range a {
+		if b[k] {
+			result[k] = true
+		}
+	}
+	return result
+}
+
+func difference[K comparable](a, b map[K]bool) map[K]bool {
+	result := make(map[K]bool, len(a))
+	for k := range a {
+		if !b[k] {
+			result[k] = true
+		}
+	}
+	return result
+}
@@ -0,0 +1,517 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"romulus/domain"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type Operator int
+
+const (
+	Equal Operator = iota
+	NotEqual
+	Less
+	LessOrEqual
+	Greater
+	GreaterOrEqual
+	Exists
+	NotExists
+	StartsWith
+	Contains
+	Matches
+	Glob
+	ContainsAny
+	ContainsAll
+)
+
+var operatorNames = map[Operator]string{
+	Equal:          "==",
+	NotEqual:       "!=",
+	Less:           "<",
+	LessOrEqual:    "<=",
+	Greater:        ">",
+	GreaterOrEqual: ">=",
+	Exists:         "exists",
+	NotExists:      "not exists",
+	StartsWith:     "startswith",
+	Contains:       "contains",
+	Matches:        "matches",
+	Glob:           "glob",
+	ContainsAny:    "contains any",
+	ContainsAll:    "contains all",
+}
+
+func (o Operator) String() string {
+	return operatorNames[o]
+}
+
+// Scope is where a predicate's key is looked up
+type Scope int
+
+const (
+	// AttributeScope is unscoped, matching either the span's attributes or its resource's
+	AttributeScope Scope = iota
+	SpanScope
+	ResourceScope
+	// EventScope matches spans with any event which has the attribute
+	EventScope
+	// LinkScope matches spans with any link which has the attribute
+	LinkScope
+	// IntrinsicScope is the fields of the span itself, such as domain.StatusField, which are
+	// kept apart from attributes so they can't collide
+	IntrinsicScope
+	// EventIntrinsicScope matches spans with any event whose own field matches, such as its name
+	EventIntrinsicScope
+)
+
+// eventNameKey is domain.EventNameField as it is stored under EventIntrinsicScope
+const eventNameKey = "name"
+
+var scopeNamespaces = map[Scope]string{
+	SpanScope:           spanAttributesNamespace,
+	ResourceScope:       resourceAttributesNamespace,
+	EventScope:          eventAttributesNamespace,
+	LinkScope:           linkAttributesNamespace,
+	IntrinsicScope:      intrinsicsNamespace,
+	EventIntrinsicScope: eventIntrinsicsNamespace,
+}
+
+var scopePrefixes = map[string]Scope{
+	domain.SpanScope:     SpanScope,
+	domain.ResourceScope: ResourceScope,
+	domain.EventScope:    EventScope,
+	domain.LinkScope:     LinkScope,
+}
+
+// ScopeOf resolves a field as written in a query into the scope and key to look it up with.
+// Intrinsic fields take precedence over attributes of the same name, and a prefix such as
+// `resource.` selects an attribute scope.
+func ScopeOf(field string) (Scope, string) {
+	if domain.IsIntrinsic(field) {
+		return IntrinsicScope, field
+	}
+
+	if field == domain.EventNameField {
+		return EventIntrinsicScope, eventNameKey
+	}
+
+	prefix, key := domain.SplitScope(field)
+	if scope, found := scopePrefixes[prefix]; found {
+		return scope, key
+	}
+	return AttributeScope, key
+}
+
+func (s Scope) namespace() string {
+	return scopeNamespaces[s]
+}
+
+// multiValued scopes store every value a span has for the attribute
+func (s Scope) multiValued() bool {
+	return s == EventScope || s == LinkScope || s == EventIntrinsicScope
+}
+
+// Predicate is a single condition on a span attribute.  Comparisons only match spans which have
+// the attribute, so `NotEqual` does not match a span without it; use NotExists for that.
+type Predicate struct {
+	Key   attribute.Key
+	Op    Operator
+	Value attribute.Value
+	// Length compares the number of elements in a slice attribute rather than its value
+	Length bool
+	Scope  Scope
+}
+
+// SpanFilter matches spans which satisfy all of its predicates
+type SpanFilter []Predicate
+
+// Is matches spans where the attribute equals the value
+func Is(kv attribute.KeyValue) Predicate {
+	return Predicate{Key: kv.Key, Op: Equal, Value: kv.Value}
+}
+
+// Compare matches spans where the attribute compares to the value with the operator
+func Compare(key string, op Operator, value attribute.Value) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: op, Value: value}
+}
+
+// Match matches spans where a string attribute, or any element of a string slice attribute,
+// satisfies one of the string operators: StartsWith, Contains, Matches (a regular expression)
+// or Glob, where `*` matches any run of characters and `?` a single character.  Contains is a
+// substring match on strings, but an exact match on the elements of string slices.
+func Match(key string, op Operator, pattern string) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: op, Value: attribute.StringValue(pattern)}
+}
+
+// Includes matches spans where a slice attribute has an element equal to the value
+func Includes(key string, value attribute.Value) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: Contains, Value: value}
+}
+
+// IncludesAny matches spans where a slice attribute has at least one of the values, which
+// must be a slice
+func IncludesAny(key string, values attribute.Value) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: ContainsAny, Value: values}
+}
+
+// IncludesAll matches spans where a slice attribute has every one of the values, which must
+// be a slice
+func IncludesAll(key string, values attribute.Value) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: ContainsAll, Value: values}
+}
+
+// CompareLength matches spans where the number of elements in a slice attribute compares to n
+// with the operator
+func CompareLength(key string, op Operator, n int) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: op, Value: attribute.IntValue(n), Length: true}
+}
+
+// In looks the predicate's key up in another scope, such as the span's intrinsic fields
+func (p Predicate) In(scope Scope) Predicate {
+	p.Scope = scope
+	return p
+}
+
+// Has matches spans with the attribute, of any type
+func Has(key string) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: Exists}
+}
+
+// Lacks matches spans without the attribute
+func Lacks(key string) Predicate {
+	return Predicate{Key: attribute.Key(key), Op: NotExists}
+}
+
+func (p Predicate) String() string {
+	if p.Length {
+		return fmt.Sprintf("len(%s) %s %s", p.Key, p.Op, p.Value.Emit())
+	}
+	if p.Op == Exists || p.Op == NotExists {
+		return fmt.Sprintf("%s %s", p.Key, p.Op)
+	}
+	return fmt.Sprintf("%s %s %s", p.Key, p.Op, p.Value.Emit())
+}
+
+// Validate checks the value suits the operator, and that patterns compile, so a bad predicate
+// can be rejected before anything is read
+func (p Predicate) Validate() error {
+	if p.Length {
+		return p.validateLength()
+	}
+
+	switch p.Op {
+	case Exists, NotExists:
+		return nil
+
+	case Equal, NotEqual:
+		if p.Value.Type() == attribute.INVALID {
+			return fmt.Errorf("%s: a value is required", p)
+		}
+		return nil
+
+	case Less, LessOrEqual, Greater, GreaterOrEqual:
+		switch p.Value.Type() {
+		case attribute.INT64, attribute.FLOAT64, attribute.STRING:
+			return nil
+		}
+		return fmt.Errorf("%s: %s values cannot be ordered", p, p.Value.Type())
+
+	case Contains:
+		if _, ok := sliceTypes[p.Value.Type()]; !ok {
+			return fmt.Errorf("%s: %s values cannot be searched for", p, p.Value.Type())
+		}
+		return nil
+
+	case ContainsAny, ContainsAll:
+		if _, ok := elementTypes[p.Value.Type()]; !ok {
+			return fmt.Errorf("%s: a list of values is required", p)
+		}
+		return nil
+
+	case StartsWith, Matches, Glob:
+		if p.Value.Type() != attribute.STRING {
+			return fmt.Errorf("%s: the pattern must be a string", p)
+		}
+		_, err := p.stringTest()
+		return err
+	}
+
+	return fmt.Errorf("unknown operator %d", p.Op)
+}
+
+func (p Predicate) validateLength() error {
+	if p.Value.Type() != attribute.INT64 {
+		return fmt.Errorf("%s: the length must be an int", p)
+	}
+
+	switch p.Op {
+	case Equal, NotEqual, Less, LessOrEqual, Greater, GreaterOrEqual:
+		return nil
+	}
+	return fmt.Errorf("%s: lengths can only be compared", p)
+}
+
+// sliceTypes maps an element type to the slice types which can contain it
+var sliceTypes = map[attribute.Type][]attribute.Type{
+	attribute.BOOL:    {attribute.BOOLSLICE},
+	attribute.INT64:   {attribute.INT64SLICE, attribute.FLOAT64SLICE},
+	attribute.FLOAT64: {attribute.INT64SLICE, attribute.FLOAT64SLICE},
+	attribute.STRING:  {attribute.STRINGSLICE},
+}
+
+var elementTypes = map[attribute.Type]attribute.Type{
+	attribute.BOOLSLICE:    attribute.BOOL,
+	attribute.INT64SLICE:   attribute.INT64,
+	attribute.FLOAT64SLICE: attribute.FLOAT64,
+	attribute.STRINGSLICE:  attribute.STRING,
+}
+
+// types are the attribute types to search.  Numbers are compared by value, so a float filter
+// also matches int attributes, and the other way around.
+func (p Predicate) types() []attribute.Type {
+	if p.Length {
+		return []attribute.Type{attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE}
+	}
+
+	switch p.Op {
+	case StartsWith, Matches, Glob:
+		return []attribute.Type{attribute.STRING, attribute.STRINGSLICE}
+
+	case Contains:
+		if p.Value.Type() == attribute.STRING {
+			return []attribute.Type{attribute.STRING, attribute.STRINGSLICE}
+		}
+		return sliceTypes[p.Value.Type()]
+
+	case ContainsAny, ContainsAll:
+		return sliceTypes[elementTypes[p.Value.Type()]]
+	}
+
+	switch p.Value.Type() {
+	case attribute.INT64, attribute.FLOAT64:
+		return []attribute.Type{attribute.INT64, attribute.FLOAT64}
+	}
+	return []attribute.Type{p.Value.Type()}
+}
+
+// matcher builds the function to evaluate stored values with, so patterns are only compiled
+// once per predicate rather than once per span
+func (p Predicate) matcher() (func(attribute.Value) bool, error) {
+	if p.Length {
+		length := Predicate{Op: p.Op, Value: p.Value}
+		return func(value attribute.Value) bool {
+			return length.matches(attribute.IntValue(len(elements(value))))
+		}, nil
+	}
+
+	switch p.Op {
+	case Contains:
+		if p.Value.Type() == attribute.STRING {
+			return func(value attribute.Value) bool {
+				if value.Type() == attribute.STRING {
+					return strings.Contains(value.AsString(), p.Value.AsString())
+				}
+				return includes(elements(value), p.Value)
+			}, nil
+		}
+
+		return func(value attribute.Value) bool {
+			return includes(elements(value), p.Value)
+		}, nil
+
+	case ContainsAny:
+		wanted := elements(p.Value)
+		return func(value attribute.Value) bool {
+			have := elements(value)
+			return slices.ContainsFunc(wanted, func(w attribute.Value) bool { return includes(have, w) })
+		}, nil
+
+	case ContainsAll:
+		wanted := elements(p.Value)
+		return func(value attribute.Value) bool {
+			have := elements(value)
+			for _, w := range wanted {
+				if !includes(have, w) {
+					return false
+				}
+			}
+			return true
+		}, nil
+
+	case StartsWith, Matches, Glob:
+		test, err := p.stringTest()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(value attribute.Value) bool {
+			switch value.Type() {
+			case attribute.STRING:
+				return test(value.AsString())
+			case attribute.STRINGSLICE:
+				return slices.ContainsFunc(value.AsStringSlice(), test)
+			}
+			return false
+		}, nil
+	}
+
+	return p.matches, nil
+}
+
+// elements splits a slice value into a value per element, and is empty for anything else
+func elements(value attribute.Value) []attribute.Value {
+	values := []attribute.Value{}
+
+	switch value.Type() {
+	case attribute.BOOLSLICE:
+		for _, b := range value.AsBoolSlice() {
+			values = append(values, attribute.BoolValue(b))
+		}
+	case attribute.INT64SLICE:
+		for _, i := range value.AsInt64Slice() {
+			values = append(values, attribute.Int64Value(i))
+		}
+	case attribute.FLOAT64SLICE:
+		for _, f := range value.AsFloat64Slice() {
+			values = append(values, attribute.Float64Value(f))
+		}
+	case attribute.STRINGSLICE:
+		for _, s := range value.AsStringSlice() {
+			values = append(values, attribute.StringValue(s))
+		}
+	}
+
+	return values
+}
+
+func includes(values []attribute.Value, wanted attribute.Value) bool {
+	return slices.ContainsFunc(values, func(v attribute.Value) bool {
+		c, ok := compareValues(v, wanted)
+		return ok && c == 0
+	})
+}
+
+func (p Predicate) stringTest() (func(string) bool, error) {
+	pattern := p.Value.AsString()
+
+	switch p.Op {
+	case StartsWith:
+		return func(s string) bool { return strings.HasPrefix(s, pattern) }, nil
+
+	case Matches:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		return re.MatchString, nil
+
+	case Glob:
+		re, err := regexp.Compile(globToRegexp(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		return re.MatchString, nil
+	}
+
+	return nil, fmt.Errorf("%s is not a string operator", p.Op)
+}
+
+// globToRegexp anchors the pattern to the whole value.  Unlike path.Match, `*` also matches `/`,
+// as most of the values being matched are routes and urls.
+func globToRegexp(pattern string) string {
+	sb := strings.Builder{}
+	sb.WriteString("^")
+
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// matches evaluates a comparison predicate against a stored value
+func (p Predicate) matches(value attribute.Value) bool {
+	switch p.Op {
+	case Equal:
+		c, ok := compareValues(value, p.Value)
+		return ok && c == 0
+	case NotEqual:
+		c, ok := compareValues(value, p.Value)
+		return !ok || c != 0
+	}
+
+	c, ok := compareValues(value, p.Value)
+	if !ok {
+		return false
+	}
+
+	switch p.Op {
+	case Less:
+		return c < 0
+	case LessOrEqual:
+		return c <= 0
+	case Greater:
+		return c > 0
+	case GreaterOrEqual:
+		return c >= 0
+	}
+
+	return false
+}
+
+// compareValues orders two values of compatible types.  Ints and floats are compared as
+// numbers, strings lexicographically, and everything else can only be equal or not.
+func compareValues(a, b attribute.Value) (int, bool) {
+	switch {
+	case a.Type() == attribute.INT64 && b.Type() == attribute.INT64:
+		return compare(a.AsInt64(), b.AsInt64()), true
+
+	case isNumeric(a) && isNumeric(b):
+		return compare(asFloat(a), asFloat(b)), true
+
+	case a.Type() == attribute.STRING && b.Type() == attribute.STRING:
+		return strings.Compare(a.AsString(), b.AsString()), true
+
+	case a.Type() == b.Type():
+		if a == b {
+			return 0, true
+		}
+		return 1, true
+	}
+
+	return 0, false
+}
+
+func compare[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isNumeric(v attribute.Value) bool {
+	return v.Type() == attribute.INT64 || v.Type() == attribute.FLOAT64
+}
+
+func asFloat(v attribute.Value) float64 {
+	if v.Type() == attribute.INT64 {
+		return float64(v.AsInt64())
+	}
+	return v.AsFloat64()
+}
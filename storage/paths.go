@@ -2,8 +2,12 @@ package storage
 
 import (
 	"fmt"
+	"net/url"
 	"path"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func spanContentPath(dataset, spanid string) string {
@@ -14,6 +18,12 @@ func tracePath(dataset string, traceid string, spanid string) string {
 	return path.Join(dataset, "traces", traceid, spanid)
 }
 
+// linkPath indexes a span under each trace it links to, so the links into a trace can be found
+// without reading every span
+func linkPath(dataset string, linkedTraceid string, spanid string) string {
+	return path.Join(dataset, "links", linkedTraceid, spanid)
+}
+
 func timesPath(dataset string, t time.Time, spanid string) string {
 	epoch := fmt.Sprint(t.Unix())
 	return path.Join(dataset, "times", epoch, spanid)
@@ -23,6 +33,70 @@ func timesPrefixPath(dataset, timePrefix string) string {
 	return path.Join(dataset, "times", timePrefix)
 }
 
-func attributePath(dataset, attrKey, valType, spanid string) string {
-	return path.Join(dataset, "attributes", attrKey+"."+valType, spanid)
+// durationPath buckets spans by the log2 of their duration in milliseconds, so a duration
+// filter only needs to list the buckets which could match.  The bucket is zero padded to keep
+// the keys sorted.
+func durationPath(dataset string, bucket int, spanid string) string {
+	return path.Join(dataset, "durations", fmt.Sprintf("%02d", bucket), spanid)
+}
+
+func durationPrefix(dataset string, bucket int) string {
+	return durationPath(dataset, bucket, "") + "/"
+}
+
+// each scope of attributes, and the intrinsics, are stored in separate namespaces with the same
+// layout.  Event and link attributes hold a json array, as a span can have many events or links
+// with the same attribute.
+const (
+	spanAttributesNamespace     = "attributes/span"
+	resourceAttributesNamespace = "attributes/resource"
+	eventAttributesNamespace    = "attributes/event"
+	linkAttributesNamespace     = "attributes/link"
+	intrinsicsNamespace         = "intrinsics"
+	eventIntrinsicsNamespace    = "intrinsics/event"
+)
+
+// attribute keys come from untrusted input, so are escaped to always be a single path segment,
+// which can't climb out of its namespace with `..` or nest with `/`
+func escapeKey(attrKey string) string {
+	return url.PathEscape(attrKey)
+}
+
+func attributePath(dataset, namespace, attrKey, valType, spanid string) string {
+	return path.Join(dataset, namespace, escapeKey(attrKey)+"."+valType, spanid)
+}
+
+// attributePrefix lists every span with the attribute, the trailing slash stops a search for
+// one type also matching a longer type name (e.g. BOOL and BOOLSLICE)
+func attributePrefix(dataset, namespace, attrKey, valType string) string {
+	return attributePath(dataset, namespace, attrKey, valType, "") + "/"
+}
+
+// attributeKeyPrefix lists every type of an attribute, as well as any attribute whose key
+// starts with this one, so results need to be checked with attributeType.
+func attributeKeyPrefix(dataset, namespace, attrKey string) string {
+	return path.Join(dataset, namespace) + "/" + escapeKey(attrKey) + "."
+}
+
+// attributeType finds the type of an attribute key found under attributeKeyPrefix, returning
+// false if the key is for a different attribute.
+func attributeType(key, attrKey string) (attribute.Type, bool) {
+	attrKey = escapeKey(attrKey)
+	dir := path.Base(path.Dir(key))
+	t, found := attributeTypes[strings.TrimPrefix(dir, attrKey+".")]
+	if !found || dir != attrKey+"."+t.String() {
+		return attribute.INVALID, false
+	}
+	return t, true
+}
+
+var attributeTypes = map[string]attribute.Type{
+	attribute.BOOL.String():         attribute.BOOL,
+	attribute.INT64.String():        attribute.INT64,
+	attribute.FLOAT64.String():      attribute.FLOAT64,
+	attribute.STRING.String():       attribute.STRING,
+	attribute.BOOLSLICE.String():    attribute.BOOLSLICE,
+	attribute.INT64SLICE.String():   attribute.INT64SLICE,
+	attribute.FLOAT64SLICE.String(): attribute.FLOAT64SLICE,
+	attribute.STRINGSLICE.String():  attribute.STRINGSLICE,
 }
@@ -4,22 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"iter"
+	"maps"
 	"path"
 	"romulus/domain"
 	"romulus/util"
+	"slices"
 	"strconv"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
 type Reader struct {
-	s3      *s3.Client
+	backend Backend
 	dataset string
+
+	// window is how many span ids Spans holds at once
+	window int
+}
+
+func NewReader(backend Backend, dataset string) *Reader {
+	return &Reader{
+		backend: backend,
+		dataset: dataset,
+		window:  spanWindowSize,
+	}
 }
 
 type Range struct {
@@ -27,142 +39,338 @@ type Range struct {
 	Finish time.Time
 }
 
-type SpanFilter []attribute.KeyValue
-
-func (s *Reader) Filter(ctx context.Context, timeRange Range, spanFilters ...SpanFilter) ([]trace.TraceID, error) {
+// Filter finds the traces with spans in the time range which match all of the expressions.
+// With no expressions, every trace in the time range matches.
+func (s *Reader) Filter(ctx context.Context, timeRange Range, exprs ...TraceExpr) ([]trace.TraceID, error) {
 	spans, err := s.spanIdsForTime(ctx, timeRange)
 	if err != nil {
 		return nil, err
 	}
 
-	traces := map[trace.TraceID]bool{}
+	traces, err := TraceAnd(exprs).matchTraces(ctx, newEvaluation(s, spans))
+	if err != nil {
+		return nil, err
+	}
 
-	for i, spanFilter := range spanFilters {
-		matches, err := s.filterSingle(ctx, spans, spanFilter)
-		if err != nil {
-			return nil, err
-		}
+	traceIds := make([]trace.TraceID, 0, len(traces))
+	for tid := range traces {
+		traceIds = append(traceIds, tid)
+	}
+
+	return traceIds, nil
+}
+
+// spanBatchSize is how many spans Spans reads concurrently before yielding them
+const spanBatchSize = 100
 
-		if i == 0 {
-			for _, match := range matches {
-				traces[match.SpanContext.TraceID()] = true
+// concurrentReads is how many spans are read from the backend at once
+const concurrentReads = 32
+
+// spanWindowSize is how many span ids Spans matches against the expression at a time, which
+// bounds its memory to around ten megabytes however long the time range is
+const spanWindowSize = 100_000
+
+// SpanAt is a span along with the epoch second it is indexed under in the times index, which is
+// the span's start time truncated to the second
+type SpanAt struct {
+	Epoch int64
+	Span  *domain.Span
+}
+
+// Spans streams the spans in the time range which match the expression, or every span in the
+// range when it is nil.  The ids in the range are taken a window at a time, in the order of the
+// times index, and the spans themselves are read in batches as the caller iterates, so memory
+// doesn't grow with the range.  The expression is matched against each window separately, which
+// means listing the index of every attribute it uses once per window.
+func (s *Reader) Spans(ctx context.Context, timeRange Range, expr SpanExpr) iter.Seq2[SpanAt, error] {
+	return func(yield func(SpanAt, error) bool) {
+		for times, err := range s.spanTimeWindows(ctx, timeRange, s.window) {
+			if err != nil {
+				yield(SpanAt{}, err)
+				return
+			}
+
+			spans := make(map[string]bool, len(times))
+			for sid := range times {
+				spans[sid] = true
 			}
-		} else {
-			tids := map[trace.TraceID]bool{}
-			for _, match := range matches {
-				tid := match.SpanContext.TraceID()
-				if _, found := traces[tid]; found {
-					tids[tid] = true
+
+			if expr != nil {
+				spans, err = expr.matchSpans(ctx, newEvaluation(s, spans), spans)
+				if err != nil {
+					yield(SpanAt{}, err)
+					return
+				}
+			}
+
+			for batch := range slices.Chunk(slices.Sorted(maps.Keys(spans)), spanBatchSize) {
+				page, err := s.readSpans(ctx, batch)
+				if err != nil {
+					yield(SpanAt{}, err)
+					return
+				}
+
+				for i, span := range page {
+					if !yield(SpanAt{Epoch: times[batch[i]], Span: span}, nil) {
+						return
+					}
 				}
 			}
-			traces = tids
 		}
 	}
+}
 
-	traceIds := make([]trace.TraceID, 0, len(traces))
-	for tid := range traces {
-		traceIds = append(traceIds, tid)
+// matchPredicate returns the subset of spans which match the predicate
+func (s *Reader) matchPredicate(ctx context.Context, spans map[string]bool, predicate Predicate) (map[string]bool, error) {
+	if predicate.Scope == IntrinsicScope && string(predicate.Key) == DurationField {
+		return s.matchDuration(ctx, spans, predicate)
 	}
 
-	return traceIds, nil
-}
+	if predicate.Scope == AttributeScope {
+		return s.matchUnscoped(ctx, spans, predicate)
+	}
 
-func (s *Reader) filterSingle(ctx context.Context, spans map[string]bool, spanFilter SpanFilter) ([]*domain.Span, error) {
-	for _, filter := range spanFilter {
+	if err := predicate.Validate(); err != nil {
+		return nil, err
+	}
 
-		prefix := attributePath(s.dataset, string(filter.Key), filter.Value.Type().String(), "")
+	switch predicate.Op {
+	case Exists:
+		return s.spansWithAttribute(ctx, spans, predicate.Scope.namespace(), string(predicate.Key))
 
-		ls, err := s.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-			Bucket: aws.String("romulus"),
-			Prefix: aws.String(prefix),
-		})
+	case NotExists:
+		with, err := s.spansWithAttribute(ctx, spans, predicate.Scope.namespace(), string(predicate.Key))
 		if err != nil {
 			return nil, err
 		}
 
-		sids := make(map[string]bool, len(spans))
-		for _, item := range ls.Contents {
-			sid := path.Base(*item.Key)
-			if _, found := spans[sid]; found {
+		return difference(spans, with), nil
+	}
 
-				value, err := s.readAttribute(ctx, string(filter.Key), filter.Value.Type(), sid)
+	// nothing left to narrow down, so skip listing the attribute entirely
+	if len(spans) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	matches, err := predicate.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	sids := make(map[string]bool, len(spans))
+	for _, attrType := range predicate.types() {
+		prefix := attributePrefix(s.dataset, predicate.Scope.namespace(), string(predicate.Key), attrType.String())
+
+		for keys, err := range listPages(ctx, s.backend, prefix) {
+			if err != nil {
+				return nil, err
+			}
+
+			for _, key := range keys {
+				sid := path.Base(key)
+				if _, found := spans[sid]; !found {
+					continue
+				}
+
+				values, err := s.readAttribute(ctx, predicate.Scope, string(predicate.Key), attrType, sid)
 				if err != nil {
 					return nil, err
 				}
 
-				// might need logic, for non strings? not sure.
-				if value == filter.Value {
+				if slices.ContainsFunc(values, matches) {
 					sids[sid] = true
 				}
 			}
 		}
+	}
+
+	return sids, nil
+}
+
+// matchUnscoped matches a predicate against either the span's or the resource's attributes.  A
+// span lacks an unscoped attribute only when neither has it.
+func (s *Reader) matchUnscoped(ctx context.Context, spans map[string]bool, predicate Predicate) (map[string]bool, error) {
+	if predicate.Op == NotExists {
+		predicate.Op = Exists
+		with, err := s.matchUnscoped(ctx, spans, predicate)
+		if err != nil {
+			return nil, err
+		}
+		return difference(spans, with), nil
+	}
 
-		spans = sids
+	sids := map[string]bool{}
+	for _, scope := range []Scope{SpanScope, ResourceScope} {
+		matched, err := s.matchPredicate(ctx, spans, predicate.In(scope))
+		if err != nil {
+			return nil, err
+		}
+		union(sids, matched)
 	}
 
-	sids := make([]string, 0, len(spans))
-	for sid := range spans {
-		sids = append(sids, sid)
+	return sids, nil
+}
+
+// spansWithAttribute returns the subset of spans which have the attribute, of any type.  The
+// value doesn't need to be read, so this is just a listing.
+func (s *Reader) spansWithAttribute(ctx context.Context, spans map[string]bool, namespace, attrKey string) (map[string]bool, error) {
+	sids := map[string]bool{}
+
+	for keys, err := range listPages(ctx, s.backend, attributeKeyPrefix(s.dataset, namespace, attrKey)) {
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range keys {
+			if _, ok := attributeType(key, attrKey); !ok {
+				continue
+			}
+
+			sid := path.Base(key)
+			if _, found := spans[sid]; found {
+				sids[sid] = true
+			}
+		}
 	}
 
-	return s.readSpans(ctx, sids)
+	return sids, nil
 }
 
-func (s *Reader) readAttribute(ctx context.Context, attrKey string, attrType attribute.Type, spanId string) (attribute.Value, error) {
-	key := attributePath(s.dataset, attrKey, attrType.String(), spanId)
+// readAttribute reads the span's values for an attribute, which is a single value except for
+// the multi valued scopes
+func (s *Reader) readAttribute(ctx context.Context, scope Scope, attrKey string, attrType attribute.Type, spanId string) ([]attribute.Value, error) {
+	key := attributePath(s.dataset, scope.namespace(), attrKey, attrType.String(), spanId)
 
-	obj, err := s.s3.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String("romulus"),
-		Key:    aws.String(key),
-	})
+	body, err := s.backend.Get(ctx, key)
 	if err != nil {
-		return attribute.Value{}, err
+		return nil, err
 	}
 
-	defer obj.Body.Close()
+	defer body.Close()
 
 	// there is probably a more efficient way to do this
 	var value any
-	if err := json.NewDecoder(obj.Body).Decode(&value); err != nil {
-		return attribute.Value{}, err
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
 	}
 
-	attr := domain.ParseValue(attrType.String(), value)
+	if !scope.multiValued() {
+		parsed, err := domain.ParseValue(attrType.String(), value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		return []attribute.Value{parsed}, nil
+	}
+
+	raw, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a list of values", key)
+	}
 
-	return attr, nil
+	values := make([]attribute.Value, len(raw))
+	for i, v := range raw {
+		parsed, err := domain.ParseValue(attrType.String(), v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		values[i] = parsed
+	}
+	return values, nil
 }
 
+// Trace reads every span of the trace.  The id must be a whole trace id, as the index is listed
+// by prefix.
 func (s *Reader) Trace(ctx context.Context, traceId string) ([]*domain.Span, error) {
-	prefix := tracePath(s.dataset, traceId, "")
-	list, err := s.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String("romulus"),
-		Prefix: aws.String(prefix),
-	})
+	tid, err := trace.TraceIDFromHex(traceId)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("trace id %q: %w", traceId, err)
 	}
 
-	spanids := make([]string, len(list.Contents))
-	for i, obj := range list.Contents {
-		spanids[i] = path.Base(*obj.Key)
-	}
-	spans, err := s.readSpans(ctx, spanids)
+	return s.readIndexed(ctx, tracePath(s.dataset, tid.String(), "")+"/")
+}
+
+// TraceTree reads a trace and links its spans into a tree.  A trace which isn't stored has no
+// spans, rather than being an error.
+func (s *Reader) TraceTree(ctx context.Context, traceId string) (*domain.Trace, error) {
+	spans, err := s.Trace(ctx, traceId)
 	if err != nil {
 		return nil, err
 	}
+
+	return domain.NewTrace(spans), nil
+}
+
+// readIndexed reads every span listed under an index prefix whose keys end in the span id
+func (s *Reader) readIndexed(ctx context.Context, prefix string) ([]*domain.Span, error) {
+	spans := []*domain.Span{}
+	for keys, err := range listPages(ctx, s.backend, prefix) {
+		if err != nil {
+			return nil, err
+		}
+
+		spanids := make([]string, len(keys))
+		for i, key := range keys {
+			spanids[i] = path.Base(key)
+		}
+
+		page, err := s.readSpans(ctx, spanids)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, page...)
+	}
+
 	return spans, nil
 }
 
+// LinkedFrom finds the spans, from any trace, which link to the trace
+func (s *Reader) LinkedFrom(ctx context.Context, traceId string) ([]*domain.Span, error) {
+	return s.readIndexed(ctx, linkPath(s.dataset, traceId, "")+"/")
+}
+
+// LinkedTrace is a trace which a span links to, with the link's attributes.  Spans is empty
+// when the linked trace isn't stored.
+type LinkedTrace struct {
+	Link  domain.Link
+	Spans []*domain.Span
+}
+
+// FollowLinks reads each of the traces the span links to, in the order of its links
+func (s *Reader) FollowLinks(ctx context.Context, span *domain.Span) ([]LinkedTrace, error) {
+	linked := make([]LinkedTrace, len(span.Links))
+	wg := errgroup.Group{}
+	for i, link := range span.Links {
+		wg.Go(func() error {
+			spans, err := s.Trace(ctx, link.SpanContext.TraceID().String())
+			if err != nil {
+				return err
+			}
+			linked[i] = LinkedTrace{Link: link, Spans: spans}
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return linked, nil
+}
+
 func (s *Reader) readSpans(ctx context.Context, spanids []string) ([]*domain.Span, error) {
 
-	var err error
 	spans := make([]*domain.Span, len(spanids))
 	wg := errgroup.Group{}
+	wg.SetLimit(concurrentReads)
 	for i, sid := range spanids {
 		wg.Go(func() error {
-			if spans[i], err = s.readSpanContents(ctx, sid); err != nil {
+			span, err := s.readSpanContents(ctx, sid)
+			if err != nil {
 				return err
 			}
+			spans[i] = span
 			return nil
 		})
 	}
@@ -175,55 +383,99 @@ func (s *Reader) readSpans(ctx context.Context, spanids []string) ([]*domain.Spa
 }
 
 func (s *Reader) spanIdsForTime(ctx context.Context, timeRange Range) (map[string]bool, error) {
-
-	start := timeRange.Start.Unix()
-	finish := timeRange.Finish.Unix()
-	prefix := util.CommonPrefix(fmt.Sprint(start), fmt.Sprint(finish))
-
-	keyPath := timesPrefixPath(s.dataset, prefix)
-
-	list, err := s.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
-		Bucket: aws.String("romulus"),
-		Prefix: aws.String(keyPath),
-	})
+	times, err := s.spanTimes(ctx, timeRange)
 	if err != nil {
 		return nil, err
 	}
 
-	spanIds := make(map[string]bool, len(list.Contents))
-	for _, obj := range list.Contents {
-		k := path.Base(path.Dir(*obj.Key))
-		ts, err := strconv.ParseInt(k, 10, 64)
+	spanIds := make(map[string]bool, len(times))
+	for sid := range times {
+		spanIds[sid] = true
+	}
+
+	return spanIds, nil
+}
+
+// spanTimes lists the spans in the time range, along with the epoch second from their key in
+// the times index.  Every span id in the range is held in memory at once, as Filter matches
+// each predicate against the whole set.  That is around a hundred bytes per span, so a range
+// with tens of millions of spans needs gigabytes, and should be narrowed instead.
+func (s *Reader) spanTimes(ctx context.Context, timeRange Range) (map[string]int64, error) {
+	times := map[string]int64{}
+	for window, err := range s.spanTimeWindows(ctx, timeRange, 0) {
 		if err != nil {
 			return nil, err
 		}
+		maps.Copy(times, window)
+	}
 
-		if ts < start {
-			continue
-		}
-		if ts > finish {
-			break
+	return times, nil
+}
+
+// spanTimeWindows yields the spans in the time range, along with their epoch second, in windows
+// of at least size spans, or a single window when size is zero.  The pages of the times index
+// are listed in order and stop at the first key past the range.
+func (s *Reader) spanTimeWindows(ctx context.Context, timeRange Range, size int) iter.Seq2[map[string]int64, error] {
+	return func(yield func(map[string]int64, error) bool) {
+		start := timeRange.Start.Unix()
+		finish := timeRange.Finish.Unix()
+		prefix := util.CommonPrefix(fmt.Sprint(start), fmt.Sprint(finish))
+
+		keyPath := timesPrefixPath(s.dataset, prefix)
+
+		times := map[string]int64{}
+		for keys, err := range listPages(ctx, s.backend, keyPath) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, key := range keys {
+				k := path.Base(path.Dir(key))
+				ts, err := strconv.ParseInt(k, 10, 64)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+
+				if ts < start {
+					continue
+				}
+				if ts > finish {
+					// keys are sorted, so nothing in the later pages can be in range either
+					if len(times) > 0 || size == 0 {
+						yield(times, nil)
+					}
+					return
+				}
+
+				times[path.Base(key)] = ts
+			}
+
+			if size > 0 && len(times) >= size {
+				if !yield(times, nil) {
+					return
+				}
+				times = map[string]int64{}
+			}
 		}
 
-		spanIds[path.Base(*obj.Key)] = true
+		if len(times) > 0 || size == 0 {
+			yield(times, nil)
+		}
 	}
-
-	return spanIds, nil
 }
 
 func (s *Reader) readSpanContents(ctx context.Context, spanId string) (*domain.Span, error) {
 	key := spanContentPath(s.dataset, spanId)
-	obj, err := s.s3.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String("romulus"),
-		Key:    aws.String(key),
-	})
+	body, err := s.backend.Get(ctx, key)
 	if err != nil {
 		return nil, fmt.Errorf("error reading key %s: %w", key, err)
 	}
-	defer obj.Body.Close()
+	defer body.Close()
 
 	span := &domain.Span{}
-	if err := json.NewDecoder(obj.Body).Decode(span); err != nil {
+	if err := json.NewDecoder(body).Decode(span); err != nil {
 		return nil, err
 	}
 
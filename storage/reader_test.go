@@ -2,7 +2,10 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"romulus/domain"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,8 +18,9 @@ import (
 
 func TestWritingSpanContents(t *testing.T) {
 	spans := createTrace()
-	writer := createTestWriter(t)
-	reader := createTestReader(t)
+	backend := createTestBackend(t)
+	writer := createTestWriter(t, backend)
+	reader := createTestReader(t, backend)
 
 	root := spans[len(spans)-1]
 	tid := root.SpanContext.TraceID()
@@ -55,10 +59,17 @@ func TestWritingSpanContents(t *testing.T) {
 		require.Len(t, read, 7)
 	})
 
+	t.Run("partial trace ids are refused", func(t *testing.T) {
+		for _, partial := range []string{"", tid.String()[:1], tid.String()[:31]} {
+			_, err := reader.Trace(t.Context(), partial)
+			require.Error(t, err, partial)
+		}
+	})
+
 	t.Run("read attributes", func(t *testing.T) {
-		attr, err := reader.readAttribute(t.Context(), "a.bool.t", attribute.BOOL, sid.String())
+		attr, err := reader.readAttribute(t.Context(), SpanScope, "a.bool.t", attribute.BOOL, sid.String())
 		require.NoError(t, err)
-		require.Equal(t, attribute.BoolValue(true), attr)
+		require.Equal(t, []attribute.Value{attribute.BoolValue(true)}, attr)
 	})
 
 	t.Run("find all spans by time", func(t *testing.T) {
@@ -83,7 +94,7 @@ func TestWritingSpanContents(t *testing.T) {
 		traceIds, err := reader.Filter(t.Context(),
 			Range{Start: root.StartTime, Finish: root.EndTime},
 			SpanFilter{
-				attribute.Bool("this.one", true),
+				Is(attribute.Bool("this.one", true)),
 			},
 		)
 		require.NoError(t, err)
@@ -94,7 +105,7 @@ func TestWritingSpanContents(t *testing.T) {
 		traceIds, err := reader.Filter(t.Context(),
 			Range{Start: root.StartTime, Finish: root.EndTime},
 			SpanFilter{
-				attribute.Bool("this.one", false),
+				Is(attribute.Bool("this.one", false)),
 			},
 		)
 		require.NoError(t, err)
@@ -105,8 +116,8 @@ func TestWritingSpanContents(t *testing.T) {
 		traceIds, err := reader.Filter(t.Context(),
 			Range{Start: root.StartTime, Finish: root.EndTime},
 			SpanFilter{
-				attribute.Bool("this.one", true),
-				attribute.Bool("other.key", false),
+				Is(attribute.Bool("this.one", true)),
+				Is(attribute.Bool("other.key", false)),
 			},
 		)
 		require.NoError(t, err)
@@ -117,10 +128,10 @@ func TestWritingSpanContents(t *testing.T) {
 		traceIds, err := reader.Filter(t.Context(),
 			Range{Start: root.StartTime, Finish: root.EndTime},
 			SpanFilter{
-				attribute.Bool("this.one", true),
+				Is(attribute.Bool("this.one", true)),
 			},
 			SpanFilter{
-				attribute.Bool("different.one", true),
+				Is(attribute.Bool("different.one", true)),
 			},
 		)
 		require.NoError(t, err)
@@ -132,10 +143,10 @@ func TestWritingSpanContents(t *testing.T) {
 		traceIds, err := reader.Filter(t.Context(),
 			Range{Start: root.StartTime, Finish: root.EndTime},
 			SpanFilter{
-				attribute.Bool("this.one", true),
+				Is(attribute.Bool("this.one", true)),
 			},
 			SpanFilter{
-				attribute.Bool("different.one", false),
+				Is(attribute.Bool("different.one", false)),
 			},
 		)
 		require.NoError(t, err)
@@ -143,6 +154,717 @@ func TestWritingSpanContents(t *testing.T) {
 	})
 }
 
+func TestSpanTimesAcrossPages(t *testing.T) {
+	backend := &countingBackend{Backend: NewMemoryBackend()}
+	reader := createTestReader(t, backend)
+
+	const base = 1729000000
+	write := func(epoch int64, count int) {
+		for i := range count {
+			sid := fmt.Sprintf("%d-%05d", epoch, i)
+			require.NoError(t, backend.Put(t.Context(), timesPath("testing", time.Unix(epoch, 0), sid), empty))
+		}
+	}
+
+	// the window spans two full pages, and the spans after it fill two more
+	write(base, listPageSize/2)
+	write(base+1, listPageSize/2)
+	write(base+2, listPageSize/2)
+	write(base+3, listPageSize/2)
+	write(base+5, listPageSize*3/2)
+
+	times, err := reader.spanTimes(t.Context(), Range{Start: time.Unix(base+1, 0), Finish: time.Unix(base+3, 0)})
+	require.NoError(t, err)
+	require.Len(t, times, listPageSize*3/2)
+
+	for sid, epoch := range times {
+		require.True(t, strings.HasPrefix(sid, fmt.Sprint(epoch)))
+	}
+
+	// listing stops at the first key past the window, on the third of four pages
+	require.EqualValues(t, 3, backend.lists.Load())
+
+	sids, err := reader.spanIdsForTime(t.Context(), Range{Start: time.Unix(base, 0), Finish: time.Unix(base+4, 0)})
+	require.NoError(t, err)
+	require.Len(t, sids, listPageSize*2)
+}
+
+func TestLargeIntegers(t *testing.T) {
+	const big = 9007199254740993
+
+	spans := createTraces([]attribute.KeyValue{
+		attribute.Int64("big", big),
+		attribute.Int64Slice("bigs", []int64{big, -big}),
+	})
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	sid := spans[0].SpanContext.SpanID().String()
+
+	read, err := reader.readAttribute(t.Context(), SpanScope, "big", attribute.INT64, sid)
+	require.NoError(t, err)
+	require.Equal(t, []attribute.Value{attribute.Int64Value(big)}, read)
+
+	read, err = reader.readAttribute(t.Context(), SpanScope, "bigs", attribute.INT64SLICE, sid)
+	require.NoError(t, err)
+	require.Equal(t, []attribute.Value{attribute.Int64SliceValue([]int64{big, -big})}, read)
+
+	span, err := reader.readSpanContents(t.Context(), sid)
+	require.NoError(t, err)
+	require.Equal(t, attribute.Int64Value(big), span.Attributes[0].Value)
+
+	matched, err := reader.matchPredicate(t.Context(), allSpans(spans), Is(attribute.Int64("big", big-1)))
+	require.NoError(t, err)
+	require.Empty(t, matched)
+
+	matched, err = reader.matchPredicate(t.Context(), allSpans(spans), Is(attribute.Int64("big", big)))
+	require.NoError(t, err)
+	require.Len(t, matched, 1)
+}
+
+func TestReadingMultiplePages(t *testing.T) {
+	count := listPageSize*2 + 500
+	spans := createWideTrace(count)
+	backend := createTestBackend(t)
+	writer := createTestWriter(t, backend)
+	reader := createTestReader(t, backend)
+
+	root := spans[len(spans)-1]
+	timeRange := Range{Start: root.StartTime, Finish: root.EndTime}
+
+	require.NoError(t, writer.Write(t.Context(), spans))
+
+	t.Run("read whole trace", func(t *testing.T) {
+		read, err := reader.Trace(t.Context(), root.SpanContext.TraceID().String())
+		require.NoError(t, err)
+		require.Len(t, read, count+1)
+	})
+
+	t.Run("find all spans by time", func(t *testing.T) {
+		sids, err := reader.spanIdsForTime(t.Context(), timeRange)
+		require.NoError(t, err)
+		require.Len(t, sids, count+1)
+	})
+
+	t.Run("find spans by attribute", func(t *testing.T) {
+		traceIds, err := reader.Filter(t.Context(), timeRange, SpanFilter{
+			Is(attribute.Bool("wide", true)),
+		})
+		require.NoError(t, err)
+		require.Len(t, traceIds, 1)
+	})
+
+	t.Run("spans are streamed a window at a time", func(t *testing.T) {
+		windowed := createTestReader(t, backend)
+		windowed.window = listPageSize
+
+		windows := 0
+		for times, err := range windowed.spanTimeWindows(t.Context(), timeRange, windowed.window) {
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(times), windowed.window+listPageSize)
+			windows++
+		}
+		require.Equal(t, 3, windows)
+
+		all, wide := 0, 0
+		for _, err := range windowed.Spans(t.Context(), timeRange, nil) {
+			require.NoError(t, err)
+			all++
+		}
+		for _, err := range windowed.Spans(t.Context(), timeRange, Is(attribute.Bool("wide", true))) {
+			require.NoError(t, err)
+			wide++
+		}
+		require.Equal(t, count+1, all)
+		require.Equal(t, count, wide)
+	})
+
+	t.Run("concurrent reads are limited", func(t *testing.T) {
+		counting := &countingBackend{Backend: backend}
+		traceIds, err := createTestReader(t, counting).Filter(t.Context(), timeRange)
+		require.NoError(t, err)
+		require.Len(t, traceIds, 1)
+		require.EqualValues(t, count+1, counting.gets.Load())
+		require.LessOrEqual(t, counting.busiest.Load(), int64(concurrentReads))
+	})
+}
+
+func TestFilterOperators(t *testing.T) {
+	spans := createTraces(
+		[]attribute.KeyValue{
+			attribute.Int("status", 200),
+			attribute.Float64("latency", 1.5),
+			attribute.String("method", "GET"),
+			attribute.Bool("ok", true),
+		},
+		[]attribute.KeyValue{
+			attribute.Int("status", 404),
+			attribute.Int("latency", 20),
+			attribute.String("method", "POST"),
+			attribute.Bool("ok", false),
+		},
+		[]attribute.KeyValue{
+			attribute.Float64("status", 500),
+			attribute.String("method", "DELETE"),
+		},
+		[]attribute.KeyValue{
+			attribute.String("method.name", "PUT"),
+		},
+	)
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	timeRange := Range{Start: spans[0].StartTime, Finish: spans[len(spans)-1].EndTime}
+
+	cases := []struct {
+		Name      string
+		Predicate Predicate
+		Expected  int
+	}{
+		{"int equal", Is(attribute.Int("status", 200)), 1},
+		{"int equal matches float", Is(attribute.Int("status", 500)), 1},
+		{"not equal skips missing", Compare("status", NotEqual, attribute.IntValue(200)), 2},
+		{"greater or equal", Compare("status", GreaterOrEqual, attribute.IntValue(404)), 2},
+		{"less", Compare("status", Less, attribute.IntValue(500)), 2},
+		{"float greater matches int", Compare("latency", Greater, attribute.Float64Value(2)), 1},
+		{"float less or equal", Compare("latency", LessOrEqual, attribute.Float64Value(1.5)), 1},
+		{"string greater", Compare("method", Greater, attribute.StringValue("GET")), 1},
+		{"string less", Compare("method", Less, attribute.StringValue("GET")), 1},
+		{"bool not equal", Compare("ok", NotEqual, attribute.BoolValue(true)), 1},
+		{"exists", Has("method"), 3},
+		{"exists any type", Has("status"), 3},
+		{"not exists", Lacks("status"), 1},
+		{"exists nested key", Has("method.name"), 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			traceIds, err := reader.Filter(t.Context(), timeRange, SpanFilter{tc.Predicate})
+			require.NoError(t, err)
+			require.Len(t, traceIds, tc.Expected)
+		})
+	}
+
+	t.Run("bools cannot be ordered", func(t *testing.T) {
+		_, err := reader.Filter(t.Context(), timeRange, SpanFilter{
+			Compare("ok", Less, attribute.BoolValue(true)),
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestFilterStringMatching(t *testing.T) {
+	spans := createTraces(
+		[]attribute.KeyValue{
+			attribute.String("http.route", "/api/v2/orders/{id}"),
+			attribute.String("db.statement", "select * from orders where id = ?"),
+		},
+		[]attribute.KeyValue{
+			attribute.String("http.route", "/api/v1/users"),
+			attribute.StringSlice("tags", []string{"checkout", "payment"}),
+		},
+		[]attribute.KeyValue{
+			attribute.String("exception.message", "connection refused: 10.0.0.1:5432"),
+			attribute.Int("http.route", 12),
+		},
+	)
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	timeRange := Range{Start: spans[0].StartTime, Finish: spans[len(spans)-1].EndTime}
+
+	cases := []struct {
+		Name      string
+		Predicate Predicate
+		Expected  int
+	}{
+		{"prefix", Match("http.route", StartsWith, "/api/v2"), 1},
+		{"prefix of all", Match("http.route", StartsWith, "/api/"), 2},
+		{"contains", Match("db.statement", Contains, "orders"), 1},
+		{"regex", Match("exception.message", Matches, `refused: \d+\.`), 1},
+		{"regex no match", Match("exception.message", Matches, `^refused`), 0},
+		{"glob", Match("http.route", Glob, "/api/*/orders/*"), 1},
+		{"glob is anchored", Match("http.route", Glob, "/api/v?"), 0},
+		{"slice any element", Match("tags", StartsWith, "pay"), 1},
+		{"slice glob", Match("tags", Glob, "check*"), 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			traceIds, err := reader.Filter(t.Context(), timeRange, SpanFilter{tc.Predicate})
+			require.NoError(t, err)
+			require.Len(t, traceIds, tc.Expected)
+		})
+	}
+
+	t.Run("invalid regex", func(t *testing.T) {
+		_, err := reader.Filter(t.Context(), timeRange, SpanFilter{Match("http.route", Matches, "(")})
+		require.Error(t, err)
+	})
+
+	t.Run("pattern must be a string", func(t *testing.T) {
+		_, err := reader.Filter(t.Context(), timeRange, SpanFilter{
+			Compare("http.route", StartsWith, attribute.IntValue(1)),
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestFilterSlices(t *testing.T) {
+	spans := createTraces(
+		[]attribute.KeyValue{
+			attribute.StringSlice("messaging.destinations", []string{"orders", "payments"}),
+			attribute.IntSlice("ports", []int{80, 443}),
+		},
+		[]attribute.KeyValue{
+			attribute.StringSlice("messaging.destinations", []string{"orders-dlq"}),
+			attribute.Float64Slice("ports", []float64{8080, 443.5}),
+			attribute.BoolSlice("flags", []bool{false, false}),
+		},
+		[]attribute.KeyValue{
+			attribute.String("messaging.destinations", "orders"),
+			attribute.StringSlice("tags", []string{}),
+		},
+	)
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	timeRange := Range{Start: spans[0].StartTime, Finish: spans[len(spans)-1].EndTime}
+
+	cases := []struct {
+		Name      string
+		Predicate Predicate
+		Expected  int
+	}{
+		{"contains element", Includes("messaging.destinations", attribute.StringValue("orders")), 2},
+		{"contains is exact for slices", Includes("messaging.destinations", attribute.StringValue("payment")), 0},
+		{"contains int", Includes("ports", attribute.IntValue(443)), 1},
+		{"contains int matches float", Includes("ports", attribute.IntValue(8080)), 1},
+		{"contains bool", Includes("flags", attribute.BoolValue(true)), 0},
+		{"contains any", IncludesAny("messaging.destinations", attribute.StringSliceValue([]string{"payments", "orders-dlq"})), 2},
+		{"contains all", IncludesAll("messaging.destinations", attribute.StringSliceValue([]string{"payments", "orders"})), 1},
+		{"contains all numbers", IncludesAll("ports", attribute.Float64SliceValue([]float64{80, 443})), 1},
+		{"length equal", CompareLength("messaging.destinations", Equal, 1), 1},
+		{"length greater", CompareLength("ports", Greater, 1), 2},
+		{"length of empty", CompareLength("tags", Equal, 0), 1},
+		{"length any type", CompareLength("flags", LessOrEqual, 2), 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			traceIds, err := reader.Filter(t.Context(), timeRange, SpanFilter{tc.Predicate})
+			require.NoError(t, err)
+			require.Len(t, traceIds, tc.Expected)
+		})
+	}
+
+	t.Run("contains any needs a list", func(t *testing.T) {
+		_, err := reader.Filter(t.Context(), timeRange, SpanFilter{IncludesAny("ports", attribute.IntValue(80))})
+		require.Error(t, err)
+	})
+
+	t.Run("length must be an int", func(t *testing.T) {
+		_, err := reader.Filter(t.Context(), timeRange, SpanFilter{
+			{Key: "ports", Op: Equal, Value: attribute.StringValue("2"), Length: true},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestFilterComposition(t *testing.T) {
+	spans := createTraces(
+		[]attribute.KeyValue{attribute.Int("status", 200), attribute.String("method", "GET")},
+		[]attribute.KeyValue{attribute.Int("status", 404), attribute.String("method", "POST")},
+		[]attribute.KeyValue{attribute.Int("status", 500), attribute.String("method", "GET")},
+		[]attribute.KeyValue{attribute.String("service", "checkout")},
+	)
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	timeRange := Range{Start: spans[0].StartTime, Finish: spans[len(spans)-1].EndTime}
+
+	cases := []struct {
+		Name     string
+		Expr     TraceExpr
+		Expected int
+	}{
+		{
+			Name: "or across traces",
+			Expr: TraceOr{
+				SpanFilter{Is(attribute.Int("status", 200))},
+				SpanFilter{Is(attribute.Int("status", 500))},
+			},
+			Expected: 2,
+		},
+		{
+			Name:     "or within a span",
+			Expr:     AnySpan{SpanOr{Is(attribute.Int("status", 404)), Is(attribute.Int("status", 500))}},
+			Expected: 2,
+		},
+		{
+			Name:     "not a trace",
+			Expr:     TraceNot{SpanFilter{Is(attribute.String("method", "GET"))}},
+			Expected: 2,
+		},
+		{
+			Name:     "not within a span includes missing attributes",
+			Expr:     AnySpan{SpanNot{Is(attribute.String("service", "checkout"))}},
+			Expected: 3,
+		},
+		{
+			Name: "and with a negation",
+			Expr: AnySpan{SpanAnd{
+				Is(attribute.String("method", "GET")),
+				SpanNot{Compare("status", GreaterOrEqual, attribute.IntValue(500))},
+			}},
+			Expected: 1,
+		},
+		{
+			Name:     "empty and matches everything",
+			Expr:     TraceAnd{},
+			Expected: 4,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			traceIds, err := reader.Filter(t.Context(), timeRange, tc.Expr)
+			require.NoError(t, err)
+			require.Len(t, traceIds, tc.Expected)
+		})
+	}
+}
+
+func TestFilterAcrossSpans(t *testing.T) {
+	spans := createTrace()
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	root := spans[len(spans)-1]
+	timeRange := Range{Start: root.StartTime, Finish: root.EndTime}
+
+	t.Run("different spans can match each part of a trace and", func(t *testing.T) {
+		traceIds, err := reader.Filter(t.Context(), timeRange, TraceAnd{
+			SpanFilter{Is(attribute.Bool("this.one", true))},
+			SpanFilter{Is(attribute.Bool("different.one", true))},
+		})
+		require.NoError(t, err)
+		require.Len(t, traceIds, 1)
+	})
+
+	t.Run("a span and must match on one span", func(t *testing.T) {
+		traceIds, err := reader.Filter(t.Context(), timeRange, AnySpan{SpanAnd{
+			Is(attribute.Bool("this.one", true)),
+			Is(attribute.Bool("different.one", true)),
+		}})
+		require.NoError(t, err)
+		require.Empty(t, traceIds)
+	})
+
+	t.Run("a trace not excludes the whole trace", func(t *testing.T) {
+		traceIds, err := reader.Filter(t.Context(), timeRange, TraceNot{
+			SpanFilter{Is(attribute.Bool("this.one", true))},
+		})
+		require.NoError(t, err)
+		require.Empty(t, traceIds)
+	})
+
+	t.Run("a span not matches the other spans", func(t *testing.T) {
+		traceIds, err := reader.Filter(t.Context(), timeRange, AnySpan{
+			SpanNot{Is(attribute.Bool("this.one", true))},
+		})
+		require.NoError(t, err)
+		require.Len(t, traceIds, 1)
+	})
+}
+
+func TestFilterIntrinsics(t *testing.T) {
+	tp, exporter := createTraceProvider()
+	start := time.Now()
+
+	ctx, root := tp.Tracer("checkout").Start(context.Background(), "root", trace.WithSpanKind(trace.SpanKindServer), trace.WithTimestamp(start))
+	_, child := tp.Tracer("database", trace.WithInstrumentationVersion("1.2.0")).Start(ctx, "query", trace.WithSpanKind(trace.SpanKindClient), trace.WithTimestamp(start))
+	child.SetAttributes(attribute.String("status", "ok"), attribute.String("name", "custom"))
+	child.SetStatus(codes.Error, "connection reset")
+	child.End(trace.WithTimestamp(start.Add(time.Second)))
+	root.End(trace.WithTimestamp(start.Add(2 * time.Second)))
+
+	spans := exporter.GetSpans()
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	cases := []struct {
+		Name      string
+		Predicate Predicate
+		Expected  string
+	}{
+		{"status", Is(attribute.String(domain.StatusField, "error")).In(IntrinsicScope), "query"},
+		{"status message", Match(domain.StatusMessageField, Contains, "reset").In(IntrinsicScope), "query"},
+		{"kind", Is(attribute.String(domain.KindField, "server")).In(IntrinsicScope), "root"},
+		{"scope", Is(attribute.String(domain.ScopeNameField, "database")).In(IntrinsicScope), "query"},
+		{"scope version", Has(domain.ScopeVersionField).In(IntrinsicScope), "query"},
+		{"root", Is(attribute.Bool(domain.RootField, true)).In(IntrinsicScope), "root"},
+		{"name", Is(attribute.String(domain.NameField, "query")).In(IntrinsicScope), "query"},
+		{"attribute with an intrinsic's name", Is(attribute.String("status", "ok")), "query"},
+		{"attribute named name", Is(attribute.String("name", "custom")), "query"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			matched, err := reader.matchPredicate(t.Context(), allSpans(spans), tc.Predicate)
+			require.NoError(t, err)
+			require.Len(t, matched, 1)
+
+			for sid := range matched {
+				span, err := reader.readSpanContents(t.Context(), sid)
+				require.NoError(t, err)
+				require.Equal(t, tc.Expected, span.Name)
+			}
+		})
+	}
+
+	t.Run("intrinsics aren't attributes", func(t *testing.T) {
+		matched, err := reader.matchPredicate(t.Context(), allSpans(spans), Is(attribute.String("kind", "server")))
+		require.NoError(t, err)
+		require.Empty(t, matched)
+	})
+}
+
+func TestFilterScopes(t *testing.T) {
+	tp, exporter := createTraceProvider()
+	tr := tp.Tracer("tests")
+	start := time.Now()
+
+	_, linked := tr.Start(context.Background(), "linked", trace.WithTimestamp(start))
+	linked.End(trace.WithTimestamp(start.Add(time.Second)))
+
+	_, span := tr.Start(context.Background(), "span", trace.WithTimestamp(start), trace.WithLinks(trace.Link{
+		SpanContext: linked.SpanContext(),
+		Attributes:  []attribute.KeyValue{attribute.String("messaging.operation", "publish")},
+	}))
+	span.SetAttributes(attribute.String("service.name", "override"))
+	span.AddEvent("exception", trace.WithAttributes(attribute.String("exception.type", "IOError")))
+	span.AddEvent("exception", trace.WithAttributes(attribute.String("exception.type", "TimeoutError"), attribute.Int("attempt", 3)))
+	span.End(trace.WithTimestamp(start.Add(time.Second)))
+
+	spans := exporter.GetSpans()
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	cases := []struct {
+		Name      string
+		Predicate Predicate
+		Expected  []string
+	}{
+		{"unscoped matches resource", Is(attribute.String("service.name", "romulus")), []string{"linked", "span"}},
+		{"unscoped matches span", Is(attribute.String("service.name", "override")), []string{"span"}},
+		{"span scope", Is(attribute.String("service.name", "romulus")).In(SpanScope), []string{}},
+		{"resource scope", Is(attribute.String("service.name", "override")).In(ResourceScope), []string{}},
+		{"unscoped lacks", Lacks("service.name"), []string{}},
+		{"span scope lacks", Lacks("service.name").In(SpanScope), []string{"linked"}},
+		{"any event", Is(attribute.String("exception.type", "TimeoutError")).In(EventScope), []string{"span"}},
+		{"other event", Is(attribute.String("exception.type", "IOError")).In(EventScope), []string{"span"}},
+		{"event number", Compare("attempt", Greater, attribute.IntValue(2)).In(EventScope), []string{"span"}},
+		{"events aren't span attributes", Has("exception.type"), []string{}},
+		{"link", Is(attribute.String("messaging.operation", "publish")).In(LinkScope), []string{"span"}},
+		{"event name", Is(attribute.String("name", "exception")).In(EventIntrinsicScope), []string{"span"}},
+		{"event name prefix", Match("name", StartsWith, "exc").In(EventIntrinsicScope), []string{"span"}},
+		{"event name isn't an attribute", Is(attribute.String("name", "exception")).In(EventScope), []string{}},
+		{"no events", Lacks("name").In(EventIntrinsicScope), []string{"linked"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			matched, err := reader.matchPredicate(t.Context(), allSpans(spans), tc.Predicate)
+			require.NoError(t, err)
+
+			names := []string{}
+			for sid := range matched {
+				span, err := reader.readSpanContents(t.Context(), sid)
+				require.NoError(t, err)
+				names = append(names, span.Name)
+			}
+			slices.Sort(names)
+
+			require.Equal(t, tc.Expected, names)
+		})
+	}
+}
+
+func TestCorrelatedEvents(t *testing.T) {
+	tp, exporter := createTraceProvider()
+	tr := tp.Tracer("tests")
+	start := time.Now()
+
+	_, span := tr.Start(context.Background(), "span", trace.WithTimestamp(start))
+	span.AddEvent("exception", trace.WithAttributes(attribute.String("exception.type", "IOError")))
+	span.AddEvent("retry", trace.WithAttributes(attribute.String("exception.type", "TimeoutError"), attribute.Int("attempt", 3)))
+	span.End(trace.WithTimestamp(start.Add(time.Second)))
+
+	spans := exporter.GetSpans()
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	name := func(n string) Predicate {
+		return Is(attribute.String("name", n)).In(EventIntrinsicScope)
+	}
+	exceptionType := func(typ string) Predicate {
+		return Is(attribute.String("exception.type", typ)).In(EventScope)
+	}
+
+	cases := []struct {
+		Name     string
+		Expr     SpanExpr
+		Expected int
+	}{
+		{"same event", SpanFilter{name("exception"), exceptionType("IOError")}, 1},
+		{"different events", SpanFilter{name("exception"), exceptionType("TimeoutError")}, 0},
+		{"three on the same event", SpanFilter{name("retry"), exceptionType("TimeoutError"), Compare("attempt", Greater, attribute.IntValue(2)).In(EventScope)}, 1},
+		{"exists on a different event", SpanFilter{exceptionType("IOError"), Has("attempt").In(EventScope)}, 0},
+		{"not exists isn't correlated", SpanFilter{name("exception"), Lacks("cause").In(EventScope)}, 1},
+		{"and of predicates", SpanAnd{name("exception"), exceptionType("TimeoutError")}, 0},
+		{"and with a filter", SpanAnd{SpanFilter{name("retry")}, exceptionType("TimeoutError")}, 1},
+		{"negated", SpanNot{Expr: SpanFilter{name("exception"), exceptionType("TimeoutError")}}, 1},
+	}
+
+	timeRange := Range{Start: start, Finish: start.Add(time.Second)}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			matched := 0
+			for _, err := range reader.Spans(t.Context(), timeRange, tc.Expr) {
+				require.NoError(t, err)
+				matched++
+			}
+			require.Equal(t, tc.Expected, matched)
+		})
+	}
+}
+
+func TestTraceTree(t *testing.T) {
+	spans := createTrace()
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	tree, err := reader.TraceTree(t.Context(), spans[0].SpanContext.TraceID().String())
+	require.NoError(t, err)
+	require.Empty(t, tree.Orphans)
+
+	names := []string{}
+	for node := range tree.Walk() {
+		names = append(names, node.Span.Name)
+	}
+	require.Equal(t, []string{"testing", "child_one", "child_two", "grand_one", "grand_two", "child_three", "grand_three"}, names)
+
+	missing, err := reader.TraceTree(t.Context(), NewTraceID().String())
+	require.NoError(t, err)
+	require.Zero(t, missing.Len())
+}
+
+func TestLinks(t *testing.T) {
+	tp, exporter := createTraceProvider()
+	tr := tp.Tracer("tests")
+
+	ctx, producer := tr.Start(context.Background(), "publish")
+	_, child := tr.Start(ctx, "serialize")
+	child.End()
+	producer.End()
+
+	missing := trace.NewSpanContext(trace.SpanContextConfig{TraceID: NewTraceID(), SpanID: NewSpanID()})
+
+	_, consumer := tr.Start(context.Background(), "consume", trace.WithLinks(
+		trace.Link{SpanContext: producer.SpanContext(), Attributes: []attribute.KeyValue{attribute.String("messaging.operation", "receive")}},
+		trace.Link{SpanContext: missing},
+	))
+	consumer.End()
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), exporter.GetSpans()))
+	reader := createTestReader(t, backend)
+
+	t.Run("linked from", func(t *testing.T) {
+		spans, err := reader.LinkedFrom(t.Context(), producer.SpanContext().TraceID().String())
+		require.NoError(t, err)
+		require.Len(t, spans, 1)
+		require.Equal(t, "consume", spans[0].Name)
+
+		spans, err = reader.LinkedFrom(t.Context(), consumer.SpanContext().TraceID().String())
+		require.NoError(t, err)
+		require.Empty(t, spans)
+	})
+
+	t.Run("follow links", func(t *testing.T) {
+		spans, err := reader.Trace(t.Context(), consumer.SpanContext().TraceID().String())
+		require.NoError(t, err)
+		require.Len(t, spans, 1)
+
+		linked, err := reader.FollowLinks(t.Context(), spans[0])
+		require.NoError(t, err)
+		require.Len(t, linked, 2)
+
+		require.Equal(t, producer.SpanContext().TraceID(), linked[0].Link.SpanContext.TraceID())
+		require.Equal(t, producer.SpanContext().SpanID(), linked[0].Link.SpanContext.SpanID())
+		require.Equal(t, []attribute.KeyValue{attribute.String("messaging.operation", "receive")}, linked[0].Link.KeyValues())
+
+		names := []string{}
+		for _, span := range linked[0].Spans {
+			names = append(names, span.Name)
+		}
+		slices.Sort(names)
+		require.Equal(t, []string{"publish", "serialize"}, names)
+
+		require.Equal(t, missing.TraceID(), linked[1].Link.SpanContext.TraceID())
+		require.Empty(t, linked[1].Spans)
+	})
+}
+
+func TestSpans(t *testing.T) {
+	spans := createTraces(
+		[]attribute.KeyValue{attribute.Int("http.status", 200)},
+		[]attribute.KeyValue{attribute.Int("http.status", 500)},
+		[]attribute.KeyValue{attribute.Int("http.status", 503)},
+	)
+
+	backend := createTestBackend(t)
+	require.NoError(t, createTestWriter(t, backend).Write(t.Context(), spans))
+	reader := createTestReader(t, backend)
+
+	timeRange := Range{Start: spans[0].StartTime, Finish: spans[len(spans)-1].EndTime}
+
+	read := func(expr SpanExpr) []string {
+		names := []string{}
+		for at, err := range reader.Spans(t.Context(), timeRange, expr) {
+			require.NoError(t, err)
+			require.Equal(t, at.Span.StartTime.Unix(), at.Epoch)
+			value, _ := at.Span.Value("http.status")
+			names = append(names, value.Emit())
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	require.Equal(t, []string{"200", "500", "503"}, read(nil))
+	require.Equal(t, []string{"500", "503"}, read(Compare("http.status", GreaterOrEqual, attribute.IntValue(500))))
+	require.Empty(t, read(Is(attribute.Int("http.status", 404))))
+}
+
 func createTrace() []domain.Span {
 	start := time.Now()
 	tp, exporter := createTraceProvider()
@@ -179,3 +901,38 @@ func createTrace() []domain.Span {
 
 	return exporter.GetSpans()
 }
+
+func createWideTrace(width int) []domain.Span {
+	start := time.Now()
+	tp, exporter := createTraceProvider()
+	tr := tp.Tracer("tests")
+
+	ctx, root := tr.Start(context.Background(), "wide", trace.WithNewRoot(), trace.WithTimestamp(start))
+
+	for i := range width {
+		ts := start.Add(time.Duration(i) * time.Millisecond)
+		_, span := tr.Start(ctx, "child", trace.WithTimestamp(ts))
+		span.SetAttributes(attribute.Bool("wide", true))
+		span.End(trace.WithTimestamp(ts))
+	}
+
+	root.End(trace.WithTimestamp(start.Add(time.Duration(width) * time.Millisecond)))
+
+	return exporter.GetSpans()
+}
+
+// createTraces makes a single span trace for each set of attributes, a second apart
+func createTraces(attrs ...[]attribute.KeyValue) []domain.Span {
+	start := time.Now()
+	tp, exporter := createTraceProvider()
+	tr := tp.Tracer("tests")
+
+	for i, set := range attrs {
+		ts := start.Add(time.Duration(i) * time.Second)
+		_, span := tr.Start(context.Background(), "span", trace.WithNewRoot(), trace.WithTimestamp(ts))
+		span.SetAttributes(set...)
+		span.End(trace.WithTimestamp(ts.Add(500 * time.Millisecond)))
+	}
+
+	return exporter.GetSpans()
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"romulus/config"
+)
+
+// NewBackend creates the backend selected by the config
+func NewBackend(ctx context.Context, cfg *config.Config) (Backend, error) {
+	switch cfg.Storage {
+	case "s3":
+		return NewS3Backend(ctx, S3Options{
+			Bucket:    cfg.Bucket,
+			KeyPrefix: cfg.KeyPrefix,
+			Endpoint:  cfg.Endpoint,
+			PathStyle: cfg.PathStyle,
+		})
+
+	case "fs":
+		return NewFileSystemBackend(cfg.StorageRoot), nil
+
+	case "memory":
+		return NewMemoryBackend(), nil
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q, expected s3, fs or memory", cfg.Storage)
+}
@@ -8,10 +8,8 @@ import (
 	"romulus/domain"
 	"sync"
 	"testing"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
@@ -93,28 +91,28 @@ func SpanFromReadOnlySpan(ro trace.ReadOnlySpan) domain.Span {
 
 	return domain.Span{
 		Name:                 ro.Name(),
-		SpanContext:          domain.SpanContext{ro.SpanContext()},
-		Parent:               domain.SpanContext{ro.Parent()},
+		SpanContext:          domain.SpanContext{SpanContext: ro.SpanContext()},
+		Parent:               domain.SpanContext{SpanContext: ro.Parent()},
 		SpanKind:             ro.SpanKind(),
 		StartTime:            ro.StartTime(),
 		EndTime:              ro.EndTime(),
 		Attributes:           fromAttributes(ro.Attributes()),
-		Events:               ro.Events(),
-		Links:                ro.Links(),
+		Events:               domain.NewEvents(ro.Events()),
+		Links:                domain.NewLinks(ro.Links()),
 		Status:               ro.Status(),
 		DroppedAttributes:    ro.DroppedAttributes(),
 		DroppedEvents:        ro.DroppedEvents(),
 		DroppedLinks:         ro.DroppedLinks(),
 		ChildSpanCount:       ro.ChildSpanCount(),
-		Resource:             &domain.Resource{ro.Resource()},
-		InstrumentationScope: ro.InstrumentationScope(),
+		Resource:             &domain.Resource{Resource: ro.Resource()},
+		InstrumentationScope: domain.Scope{Scope: ro.InstrumentationScope()},
 	}
 }
 
 func fromAttributes(attrs []attribute.KeyValue) []domain.Attribute {
 	wrapped := make([]domain.Attribute, len(attrs))
 	for i, attr := range attrs {
-		wrapped[i] = domain.Attribute{attr}
+		wrapped[i] = domain.Attribute{KeyValue: attr}
 	}
 	return wrapped
 }
@@ -144,33 +142,38 @@ func NewSpanID() oteltrace.SpanID {
 	return sid
 }
 
-func createTestWriter(t *testing.T) *Writer {
-	cfg, err := config.LoadDefaultConfig(t.Context())
-	require.NoError(t, err)
-
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = true
-	})
-	require.NotNil(t, client)
-
-	return &Writer{
-		s3:      client,
-		dataset: "testing",
-	}
+// NewTestSpans creates a trace of two spans for the tests of the packages using storage: a root
+// lasting 3s from start, and a child lasting 1s from a second in, which has an
+// http.status_code of 500, retry = true and two messaging.destinations
+func NewTestSpans(start time.Time) []domain.Span {
+	exporter := NewInMemoryExporter()
+	tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+	tr := tp.Tracer("tests")
+
+	ctx, root := tr.Start(context.Background(), "root", oteltrace.WithTimestamp(start))
+	_, child := tr.Start(ctx, "child", oteltrace.WithTimestamp(start.Add(time.Second)))
+	child.SetAttributes(
+		attribute.Int("http.status_code", 500),
+		attribute.Bool("retry", true),
+		attribute.StringSlice("messaging.destinations", []string{"orders", "payments"}),
+	)
+	child.End(oteltrace.WithTimestamp(start.Add(2 * time.Second)))
+	root.End(oteltrace.WithTimestamp(start.Add(3 * time.Second)))
 
+	return exporter.GetSpans()
 }
 
-func createTestReader(t *testing.T) *Reader {
-	cfg, err := config.LoadDefaultConfig(t.Context())
-	require.NoError(t, err)
+func createTestBackend(t *testing.T) Backend {
+	t.Helper()
+	return NewMemoryBackend()
+}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = true
-	})
-	require.NotNil(t, client)
+func createTestWriter(t *testing.T, backend Backend) *Writer {
+	t.Helper()
+	return NewWriter(backend, "testing")
+}
 
-	return &Reader{
-		s3:      client,
-		dataset: "testing",
-	}
+func createTestReader(t *testing.T, backend Backend) *Reader {
+	t.Helper()
+	return NewReader(backend, "testing")
 }
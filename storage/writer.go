@@ -1,21 +1,32 @@
 package storage
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"romulus/domain"
+	"slices"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrInvalidSpan is returned for a span which can never be stored, such as one with a NaN
+// attribute, so retrying the write is pointless
+var ErrInvalidSpan = errors.New("invalid span")
+
 type Writer struct {
-	s3      *s3.Client
+	backend Backend
 	dataset string
 }
 
+func NewWriter(backend Backend, dataset string) *Writer {
+	return &Writer{
+		backend: backend,
+		dataset: dataset,
+	}
+}
+
 func (s *Writer) Write(ctx context.Context, spans []domain.Span) error {
 	if len(spans) == 0 {
 		return nil
@@ -25,16 +36,21 @@ func (s *Writer) Write(ctx context.Context, spans []domain.Span) error {
 	// we could also use go routines to write the spans in parallel.  Leaving this as is for now, as
 	// its easier to debug sequential code, and I am not certain on the api usage yet.
 
-	for _, span := range spans {
-		sc := span.SpanContext
-		sid := sc.SpanContext.SpanID().String()
-
+	// every span is encoded before anything is written, so an invalid span stores nothing
+	contents := make([][]byte, len(spans))
+	for i, span := range spans {
 		content, err := json.Marshal(span)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w %s: %w", ErrInvalidSpan, span.SpanContext.SpanID(), err)
 		}
+		contents[i] = content
+	}
+
+	for i, span := range spans {
+		sc := span.SpanContext
+		sid := sc.SpanContext.SpanID().String()
 
-		if err := s.put(ctx, spanContentPath(s.dataset, sid), content); err != nil {
+		if err := s.put(ctx, spanContentPath(s.dataset, sid), contents[i]); err != nil {
 			return err
 		}
 
@@ -46,6 +62,19 @@ func (s *Writer) Write(ctx context.Context, spans []domain.Span) error {
 			return err
 		}
 
+		for _, link := range span.Links {
+			if !link.SpanContext.TraceID().IsValid() {
+				continue
+			}
+			if err := s.put(ctx, linkPath(s.dataset, link.SpanContext.TraceID().String(), sid), empty); err != nil {
+				return err
+			}
+		}
+
+		if err := s.writeDuration(ctx, span); err != nil {
+			return err
+		}
+
 		if err := s.writeAttributes(ctx, span); err != nil {
 			return err
 		}
@@ -62,52 +91,111 @@ var empty = []byte{}
 func (s *Writer) writeAttributes(ctx context.Context, span domain.Span) error {
 	spanId := span.SpanContext.SpanID().String()
 
-	writeAttr := func(attr attribute.KeyValue) error {
-		key := attributePath(s.dataset, string(attr.Key), attr.Value.Type().String(), spanId)
-		value, err := json.Marshal(attr.Value.AsInterface())
+	write := func(namespace string, attrKey attribute.Key, attrType attribute.Type, content any) error {
+		key := attributePath(s.dataset, namespace, string(attrKey), attrType.String(), spanId)
+		value, err := json.Marshal(content)
 		if err != nil {
 			return err
 		}
-		if err := s.put(ctx, key, value); err != nil {
-			return err
-		}
 
-		return nil
+		return s.put(ctx, key, value)
 	}
 
-	// write attributes
-	// write resources
-	// write meta
-	for _, attr := range span.Attributes {
-		if err := writeAttr(attr.KeyValue); err != nil {
-			return err
+	writeAttrs := func(namespace string, attrs []attribute.KeyValue) error {
+		for _, attr := range attrs {
+			if err := write(namespace, attr.Key, attr.Value.Type(), attr.Value.AsInterface()); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 
-	for _, attr := range span.Resource.Attributes() {
-		if err := writeAttr(attr); err != nil {
-			return err
+	// multi valued attributes are grouped by key and type, so each is written once with all of
+	// its values
+	writeMulti := func(namespace string, sets [][]attribute.KeyValue) error {
+		type field struct {
+			key      attribute.Key
+			attrType attribute.Type
+		}
+
+		order := []field{}
+		values := map[field][]any{}
+		for _, attrs := range sets {
+			for _, attr := range attrs {
+				f := field{attr.Key, attr.Value.Type()}
+				if _, found := values[f]; !found {
+					order = append(order, f)
+				}
+				values[f] = append(values[f], attr.Value.AsInterface())
+			}
 		}
+
+		for _, f := range order {
+			if err := write(namespace, f.key, f.attrType, values[f]); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
-	if err := writeAttr(attribute.String("name", span.Name)); err != nil {
+	spanAttrs := make([]attribute.KeyValue, len(span.Attributes))
+	for i, attr := range span.Attributes {
+		spanAttrs[i] = attr.KeyValue
+	}
+
+	if err := writeAttrs(spanAttributesNamespace, spanAttrs); err != nil {
 		return err
 	}
 
-	return nil
-}
+	if err := writeAttrs(resourceAttributesNamespace, span.Resource.Attributes()); err != nil {
+		return err
+	}
 
-// low level api
-func (s *Writer) put(ctx context.Context, path string, content []byte) error {
-	// fmt.Println("put:", path)
-	_, err := s.s3.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String("romulus"),
-		Key:    aws.String(path),
-		Body:   bytes.NewReader(content),
+	events := make([][]attribute.KeyValue, len(span.Events))
+	names := make([][]attribute.KeyValue, len(span.Events))
+	for i, event := range span.Events {
+		events[i] = event.KeyValues()
+		names[i] = []attribute.KeyValue{attribute.String(eventNameKey, event.Name)}
+	}
+	if err := writeMulti(eventAttributesNamespace, events); err != nil {
+		return err
+	}
+	if err := writeMulti(eventIntrinsicsNamespace, names); err != nil {
+		return err
+	}
+
+	links := make([][]attribute.KeyValue, len(span.Links))
+	for i, link := range span.Links {
+		links[i] = link.KeyValues()
+	}
+	if err := writeMulti(linkAttributesNamespace, links); err != nil {
+		return err
+	}
+
+	// durations have their own index, see writeDuration
+	intrinsics := slices.DeleteFunc(span.Intrinsics(), func(kv attribute.KeyValue) bool {
+		return kv.Key == DurationField
 	})
+
+	return writeAttrs(intrinsicsNamespace, intrinsics)
+}
+
+// writeDuration stores the exact duration, so spans in a partially matching bucket can be
+// checked without reading the whole span
+func (s *Writer) writeDuration(ctx context.Context, span domain.Span) error {
+	d := span.EndTime.Sub(span.StartTime)
+	key := durationPath(s.dataset, durationBucket(d), span.SpanContext.SpanID().String())
+
+	content, err := json.Marshal(d)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return s.put(ctx, key, content)
+}
+
+// low level api
+func (s *Writer) put(ctx context.Context, path string, content []byte) error {
+	// fmt.Println("put:", path)
+	return s.backend.Put(ctx, path, content)
 }
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"math"
+	"romulus/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWriteInvalidSpan(t *testing.T) {
+	span := func(sid byte, attrs ...attribute.KeyValue) domain.Span {
+		wrapped := make([]domain.Attribute, len(attrs))
+		for i, attr := range attrs {
+			wrapped[i] = domain.Attribute{KeyValue: attr}
+		}
+
+		return domain.Span{
+			Name:        "span",
+			SpanContext: domain.SpanContext{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{sid}})},
+			StartTime:   time.Now(),
+			EndTime:     time.Now(),
+			Attributes:  wrapped,
+			Resource:    &domain.Resource{Resource: resource.Empty()},
+		}
+	}
+
+	backend := NewMemoryBackend()
+	err := NewWriter(backend, "default").Write(t.Context(), []domain.Span{
+		span(1),
+		span(2, attribute.Float64("ratio", math.NaN())),
+	})
+	require.ErrorIs(t, err, ErrInvalidSpan)
+
+	// the valid span before it wasn't written either
+	page, err := backend.List(t.Context(), "", "")
+	require.NoError(t, err)
+	require.Empty(t, page.Keys)
+}
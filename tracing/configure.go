@@ -2,10 +2,7 @@ package tracing
 
 import (
 	"context"
-	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"go.opentelemetry.io/otel"
 	otlpgrpc "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
@@ -39,19 +36,5 @@ func Configure(ctx context.Context, appName string, version string) (func(ctx co
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.TraceContext{})
 
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		s := <-signals
-		fmt.Printf("Received %s, stopping\n", s)
-
-		if err := tp.Shutdown(context.Background()); err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			os.Exit(1)
-		}
-
-		os.Exit(0)
-	}()
-
 	return tp.Shutdown, nil
 }